@@ -0,0 +1,273 @@
+// contentfilter/contentfilter.go
+package contentfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Actions taken on filtered content, from the mildest to the strictest
+const (
+	Allow  = "allow"
+	Mask   = "mask"
+	Hold   = "hold"
+	Reject = "reject"
+)
+
+var severity = map[string]int{Allow: 0, Mask: 1, Hold: 2, Reject: 3}
+
+// WordList is a list of words of one language and the action taken when one is used
+type WordList struct {
+	Action string   `json:"action"`
+	Words  []string `json:"words"`
+}
+
+// Rule is a regular expression matched against the text
+type Rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+}
+
+// Config is the moderation pipeline read from the CONTENT_FILTER_CONFIG file. Word
+// lists are keyed by language code.
+type Config struct {
+	Languages   map[string]WordList `json:"languages"`
+	Rules       []Rule              `json:"rules"`
+	MaxLinks    int                 `json:"max_links"`
+	LinksAction string              `json:"links_action"`
+}
+
+// DefaultConfig is used when no configuration file is set: posts with many links are
+// held for review.
+var DefaultConfig = Config{MaxLinks: 3, LinksAction: Hold}
+
+// Verdict is the outcome of filtering a text. Text is the text with the masked
+// matches replaced.
+type Verdict struct {
+	Action  string
+	Text    string
+	Reasons []string
+}
+
+// matcher finds one kind of unwanted content in a text
+type matcher struct {
+	reason string
+	action string
+	find   func(text string) [][]int
+}
+
+// Filter applies the configured word lists, rules and link limit to texts
+type Filter struct {
+	matchers    []matcher
+	maxLinks    int
+	linksAction string
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+var filter, _ = New(DefaultConfig)
+
+// InitFilter loads the moderation pipeline from CONTENT_FILTER_CONFIG, restricted to
+// the word lists of CONTENT_FILTER_LANGUAGES when set
+func InitFilter() {
+	config := DefaultConfig
+	if path := os.Getenv("CONTENT_FILTER_CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			panic("Failed to read CONTENT_FILTER_CONFIG: " + err.Error())
+		}
+		config = Config{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			panic("Invalid CONTENT_FILTER_CONFIG: " + err.Error())
+		}
+	}
+
+	if languages := os.Getenv("CONTENT_FILTER_LANGUAGES"); languages != "" {
+		enabled := map[string]WordList{}
+		for _, language := range strings.Split(languages, ",") {
+			language = strings.TrimSpace(language)
+			if list, ok := config.Languages[language]; ok {
+				enabled[language] = list
+			}
+		}
+		config.Languages = enabled
+	}
+
+	f, err := New(config)
+	if err != nil {
+		panic(err.Error())
+	}
+	filter = f
+}
+
+// GetFilter returns the configured filter
+func GetFilter() *Filter {
+	return filter
+}
+
+// New compiles a moderation pipeline
+func New(config Config) (*Filter, error) {
+	f := &Filter{maxLinks: config.MaxLinks, linksAction: config.LinksAction}
+	if f.maxLinks > 0 && !validAction(f.linksAction) {
+		return nil, fmt.Errorf("content filter: invalid links action %q", f.linksAction)
+	}
+
+	languages := make([]string, 0, len(config.Languages))
+	for language := range config.Languages {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		list := config.Languages[language]
+		if !validAction(list.Action) {
+			return nil, fmt.Errorf("content filter: invalid action %q for language %s", list.Action, language)
+		}
+		if find := wordFinder(list.Words); find != nil {
+			f.matchers = append(f.matchers, matcher{reason: "blocked word (" + language + ")", action: list.Action, find: find})
+		}
+	}
+
+	for _, rule := range config.Rules {
+		if !validAction(rule.Action) {
+			return nil, fmt.Errorf("content filter: invalid action %q for rule %s", rule.Action, rule.Name)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("content filter: invalid pattern for rule %s: %v", rule.Name, err)
+		}
+		f.matchers = append(f.matchers, matcher{reason: rule.Name, action: rule.Action, find: func(text string) [][]int {
+			return re.FindAllStringIndex(text, -1)
+		}})
+	}
+
+	return f, nil
+}
+
+// Check runs a text through the pipeline. The strictest action of all matches wins.
+func (f *Filter) Check(text string) Verdict {
+	verdict := Verdict{Action: Allow, Text: text}
+	var masked [][]int
+
+	for _, m := range f.matchers {
+		matches := m.find(text)
+		if len(matches) == 0 {
+			continue
+		}
+		verdict.apply(m.action, m.reason)
+		if m.action == Mask {
+			masked = append(masked, matches...)
+		}
+	}
+
+	if f.maxLinks > 0 {
+		links := linkPattern.FindAllStringIndex(text, -1)
+		if len(links) > f.maxLinks {
+			verdict.apply(f.linksAction, fmt.Sprintf("more than %d links", f.maxLinks))
+			if f.linksAction == Mask {
+				masked = append(masked, links...)
+			}
+		}
+	}
+
+	if len(masked) > 0 {
+		verdict.Text = maskRanges(text, masked)
+	}
+	return verdict
+}
+
+// apply records a match, keeping the strictest action
+func (v *Verdict) apply(action, reason string) {
+	if severity[action] > severity[v.Action] {
+		v.Action = action
+	}
+	v.Reasons = append(v.Reasons, reason)
+}
+
+// CountLinks returns how many links the text contains
+func CountLinks(text string) int {
+	return len(linkPattern.FindAllStringIndex(text, -1))
+}
+
+// ContainsWord reports whether the text contains one of the words as a whole word,
+// ignoring case
+func ContainsWord(text string, words []string) bool {
+	find := wordFinder(words)
+	return find != nil && len(find(text)) > 0
+}
+
+// wordFinder returns a function finding whole word, case-insensitive occurrences of
+// the words, or nil when there are no words
+func wordFinder(words []string) func(text string) [][]int {
+	var quoted []string
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+
+	// Longer words first so phrases win over the words they contain
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	re := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+
+	return func(text string) [][]int {
+		var matches [][]int
+		for _, match := range re.FindAllStringIndex(text, -1) {
+			if isWordBoundary(text, match[0], match[1]) {
+				matches = append(matches, match)
+			}
+		}
+		return matches
+	}
+}
+
+// isWordBoundary reports whether text[start:end] is not part of a longer word
+func isWordBoundary(text string, start, end int) bool {
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// maskRanges replaces everything but whitespace inside the byte ranges with asterisks
+func maskRanges(text string, ranges [][]int) string {
+	inRange := func(i int) bool {
+		for _, r := range ranges {
+			if i >= r[0] && i < r[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var b strings.Builder
+	for i, r := range text {
+		if inRange(i) && !unicode.IsSpace(r) {
+			b.WriteRune('*')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func validAction(action string) bool {
+	_, ok := severity[action]
+	return ok
+}
@@ -0,0 +1,127 @@
+package contentfilter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testFilter(t *testing.T) *Filter {
+	t.Helper()
+	f, err := New(Config{
+		Languages: map[string]WordList{
+			"en": {Action: Mask, Words: []string{"darn", "heck no"}},
+			"id": {Action: Hold, Words: []string{"bodoh"}},
+		},
+		Rules: []Rule{
+			{Name: "giveaway", Pattern: `(?i)free\s+bitcoin`, Action: Reject},
+		},
+		MaxLinks:    2,
+		LinksAction: Hold,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestCheck(t *testing.T) {
+	f := testFilter(t)
+	tests := []struct {
+		name    string
+		text    string
+		action  string
+		masked  string
+		reasons int
+	}{
+		{"clean", "a lovely sunset", Allow, "a lovely sunset", 0},
+		{"masked word", "Darn, that view", Mask, "****, that view", 1},
+		{"phrase keeps spaces", "well heck no!", Mask, "well **** **!", 1},
+		{"part of a longer word", "darnedest thing", Allow, "darnedest thing", 0},
+		{"strictest action wins", "darn bodoh", Hold, "**** bodoh", 2},
+		{"rule", "FREE  Bitcoin here", Reject, "FREE  Bitcoin here", 1},
+		{"links under the limit", "see https://a.example and www.b.example", Allow, "see https://a.example and www.b.example", 0},
+		{"too many links", "http://a.example http://b.example http://c.example", Hold, "http://a.example http://b.example http://c.example", 1},
+	}
+	for _, tt := range tests {
+		verdict := f.Check(tt.text)
+		if verdict.Action != tt.action || verdict.Text != tt.masked || len(verdict.Reasons) != tt.reasons {
+			t.Errorf("%s: got %s %q %v; want %s %q with %d reasons",
+				tt.name, verdict.Action, verdict.Text, verdict.Reasons, tt.action, tt.masked, tt.reasons)
+		}
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	configs := map[string]Config{
+		"language action": {Languages: map[string]WordList{"en": {Action: "delete", Words: []string{"x"}}}},
+		"rule action":     {Rules: []Rule{{Name: "r", Pattern: "x", Action: ""}}},
+		"rule pattern":    {Rules: []Rule{{Name: "r", Pattern: "(", Action: Hold}}},
+		"links action":    {MaxLinks: 1, LinksAction: "block"},
+	}
+	for name, config := range configs {
+		if _, err := New(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := New(Config{}); err != nil {
+		t.Errorf("empty config: %v", err)
+	}
+}
+
+func TestMaskRanges(t *testing.T) {
+	tests := []struct {
+		text   string
+		ranges [][]int
+		want   string
+	}{
+		{"hello world", [][]int{{0, 5}}, "***** world"},
+		{"hello world", [][]int{{0, 11}}, "***** *****"},
+		{"ab cd ef", [][]int{{0, 2}, {6, 8}}, "** cd **"},
+		{"héllo there", [][]int{{0, 6}}, "***** there"},
+		{"overlap", [][]int{{0, 4}, {2, 7}}, "*******"},
+		{"untouched", nil, "untouched"},
+	}
+	for _, tt := range tests {
+		if got := maskRanges(tt.text, tt.ranges); got != tt.want {
+			t.Errorf("maskRanges(%q, %v) = %q, want %q", tt.text, tt.ranges, got, tt.want)
+		}
+	}
+}
+
+func TestContainsWord(t *testing.T) {
+	words := []string{" Spoiler ", "", "plot twist"}
+	tests := map[string]bool{
+		"no spoilers here":     false,
+		"SPOILER: it was him":  true,
+		"what a plot twist!":   true,
+		"the plot twisted":     false,
+		"nothing to see":       false,
+		"spoiler_alert tagged": false,
+	}
+	for text, want := range tests {
+		if got := ContainsWord(text, words); got != want {
+			t.Errorf("ContainsWord(%q) = %v, want %v", text, got, want)
+		}
+	}
+	if ContainsWord("anything", nil) {
+		t.Error("no words should never match")
+	}
+}
+
+func TestCountLinks(t *testing.T) {
+	if got := CountLinks("http://a.example, https://b.example/x?y and www.c.example"); got != 3 {
+		t.Errorf("got %d links, want 3", got)
+	}
+	if got := CountLinks("example.com is not a link"); got != 0 {
+		t.Errorf("got %d links, want 0", got)
+	}
+}
+
+func TestVerdictReasonsOrder(t *testing.T) {
+	verdict := testFilter(t).Check("bodoh darn")
+	want := []string{"blocked word (en)", "blocked word (id)"}
+	if !reflect.DeepEqual(verdict.Reasons, want) {
+		t.Errorf("got reasons %v, want %v", verdict.Reasons, want)
+	}
+}
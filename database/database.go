@@ -31,7 +31,7 @@ func InitDB() {
 		panic("One or more environment variables are not set")
 	}
 
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", user, password, host, port, dbName)
+	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", user, password, host, port, dbName)
 
 	conn, err := gorm.Open("mysql", dataSourceName)
 	if err != nil {
@@ -10,4 +10,33 @@ func AutoMigrate() {
 	db.AutoMigrate(&models.SocialMedia{})
 	db.AutoMigrate(&models.Photo{})
 	db.AutoMigrate(&models.Comment{})
+	db.AutoMigrate(&models.Webhook{})
+	db.AutoMigrate(&models.WebhookDelivery{})
+	db.AutoMigrate(&models.WebhookAttempt{})
+	db.AutoMigrate(&models.PhotoVariant{})
+	db.AutoMigrate(&models.Album{})
+	db.AutoMigrate(&models.AlbumPhoto{})
+	db.AutoMigrate(&models.Follow{})
+	db.AutoMigrate(&models.Block{})
+	db.AutoMigrate(&models.Mute{})
+	db.AutoMigrate(&models.Report{})
+	db.AutoMigrate(&models.ModerationAction{})
+	db.AutoMigrate(&models.Like{})
+	db.AutoMigrate(&models.PhotoTag{})
+	db.AutoMigrate(&models.PhotoScore{})
+	db.AutoMigrate(&models.Bookmark{})
+	db.AutoMigrate(&models.BookmarkCollection{})
+	db.AutoMigrate(&models.Conversation{})
+	db.AutoMigrate(&models.ConversationParticipant{})
+	db.AutoMigrate(&models.Message{})
+	db.AutoMigrate(&models.Story{})
+	db.AutoMigrate(&models.StoryView{})
+	db.AutoMigrate(&models.PhotoMedia{})
+	db.AutoMigrate(&models.PhotoRevision{})
+	db.AutoMigrate(&models.CommentRevision{})
+	db.AutoMigrate(&models.SpamScore{})
+	db.Set("gorm:table_options", "CHARSET=utf8mb4").AutoMigrate(&models.IdempotencyKey{})
+	db.Set("gorm:table_options", "CHARSET=utf8mb4").AutoMigrate(&models.HiddenWord{})
+	// Reaction emoji need four byte UTF-8
+	db.Set("gorm:table_options", "CHARSET=utf8mb4").AutoMigrate(&models.CommentReaction{})
 }
@@ -2,15 +2,19 @@ module github.com/faazabilamri7/mygram
 
 go 1.22.1
 
+require (
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/go-sql-driver/mysql v1.8.0
+	github.com/gorilla/mux v1.8.1
+	github.com/jinzhu/gorm v1.9.16
+	github.com/jinzhu/inflection v1.0.0
+	github.com/joho/godotenv v1.5.1
+	golang.org/x/crypto v0.21.0
+	golang.org/x/image v0.18.0
+	gorm.io/gorm v1.25.8
+)
+
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
-	github.com/go-sql-driver/mysql v1.8.0 // indirect
-	github.com/gorilla/mux v1.8.1 // indirect
-	github.com/jinzhu/gorm v1.9.16 // indirect
-	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
-	golang.org/x/crypto v0.21.0 // indirect
-	gorm.io/gorm v1.25.8 // indirect
 )
@@ -0,0 +1,481 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/listing"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/gorilla/mux"
+)
+
+// CreateAlbum handles the creation of a new album, optionally with its initial photos
+func CreateAlbum(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Title        string `json:"title"`
+		Description  string `json:"description"`
+		Visibility   string `json:"visibility"`
+		CoverPhotoID *uint  `json:"cover_photo_id"`
+		PhotoIDs     []uint `json:"photo_ids"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	visibility, ok := normalizeAlbumVisibility(req.Visibility)
+	if !ok {
+		http.Error(w, "Invalid album visibility", http.StatusBadRequest)
+		return
+	}
+
+	// Only the user's own photos can be added, each at most once
+	photoIDs := uniqueIDs(req.PhotoIDs)
+	if !ownsPhotos(userID, photoIDs) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if req.CoverPhotoID != nil && !containsID(photoIDs, *req.CoverPhotoID) {
+		http.Error(w, "Cover photo must be part of the album", http.StatusBadRequest)
+		return
+	}
+
+	album := models.Album{
+		UserID:       userID,
+		Title:        req.Title,
+		Description:  req.Description,
+		Visibility:   visibility,
+		CoverPhotoID: req.CoverPhotoID,
+	}
+
+	// Save album and its membership in one transaction
+	tx := database.GetDB().Begin()
+	err = tx.Create(&album).Error
+	for i, photoID := range photoIDs {
+		if err != nil {
+			break
+		}
+		err = tx.Create(&models.AlbumPhoto{AlbumID: album.ID, PhotoID: photoID, Position: i}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to create album", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to create album", http.StatusInternalServerError)
+		return
+	}
+
+	loadAlbumPhotos(&album, album.UserID)
+
+	// Set appropriate response status and return the created album
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(album)
+}
+
+// GetAllAlbums handles fetching a page of albums; other users' private albums are left out
+func GetAllAlbums(w http.ResponseWriter, r *http.Request) {
+	query, err := listing.Parse(r.URL.Query(), albumListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewerID := getOptionalUserID(r)
+	ownerCondition, ownerArgs := visibleUserCondition("albums.user_id", viewerID)
+	notMuted, mutedArgs := notMutedCondition("albums.user_id", viewerID)
+
+	var albums []models.Album
+	err = database.GetDB().Where("albums.visibility = ? OR albums.user_id = ?", models.AlbumPublic, viewerID).
+		Where(ownerCondition, ownerArgs...).Where(notMuted, mutedArgs...).
+		Scopes(query.Scope).Find(&albums).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch albums", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched page of albums
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listing.Paginate(query, albums))
+}
+
+// GetAlbumByID handles fetching an album with its photos in order
+func GetAlbumByID(w http.ResponseWriter, r *http.Request) {
+	album, ok := findAlbum(w, r)
+	if !ok {
+		return
+	}
+
+	// Private albums are only visible to their owner, albums of private accounts to approved followers
+	viewerID := getOptionalUserID(r)
+	if (album.Visibility == models.AlbumPrivate && album.UserID != viewerID) || !canViewUserContent(viewerID, album.UserID) {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return
+	}
+
+	loadAlbumPhotos(&album, viewerID)
+
+	// Set appropriate response status and return the fetched album
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(album)
+}
+
+// UpdateAlbumByID handles updating an album's details, visibility and cover photo
+func UpdateAlbumByID(w http.ResponseWriter, r *http.Request) {
+	album, ok := findOwnedAlbum(w, r)
+	if !ok {
+		return
+	}
+
+	// An omitted cover_photo_id keeps the cover, null clears it
+	var updatedAlbum struct {
+		models.Album
+		CoverPhotoID json.RawMessage `json:"cover_photo_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&updatedAlbum)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	coverPhotoID := album.CoverPhotoID
+	if updatedAlbum.CoverPhotoID != nil {
+		coverPhotoID = nil
+		if err := json.Unmarshal(updatedAlbum.CoverPhotoID, &coverPhotoID); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// An omitted visibility keeps the current one
+	if updatedAlbum.Visibility == "" {
+		updatedAlbum.Visibility = album.Visibility
+	}
+	visibility, ok := normalizeAlbumVisibility(updatedAlbum.Visibility)
+	if !ok {
+		http.Error(w, "Invalid album visibility", http.StatusBadRequest)
+		return
+	}
+
+	// The cover photo has to be one of the album's photos
+	if coverPhotoID != nil {
+		var count int
+		database.GetDB().Model(&models.AlbumPhoto{}).
+			Where("album_id = ? AND photo_id = ?", album.ID, *coverPhotoID).Count(&count)
+		if count == 0 {
+			http.Error(w, "Cover photo must be part of the album", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Update album fields
+	album.Title = updatedAlbum.Title
+	album.Description = updatedAlbum.Description
+	album.Visibility = visibility
+	album.CoverPhotoID = coverPhotoID
+
+	// Save updated album to the database
+	err = database.GetDB().Save(&album).Error
+	if err != nil {
+		http.Error(w, "Failed to update album", http.StatusInternalServerError)
+		return
+	}
+
+	loadAlbumPhotos(&album, album.UserID)
+
+	// Set appropriate response status and return the updated album
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(album)
+}
+
+// DeleteAlbumByID handles deleting an album; its photos are kept
+func DeleteAlbumByID(w http.ResponseWriter, r *http.Request) {
+	album, ok := findOwnedAlbum(w, r)
+	if !ok {
+		return
+	}
+
+	// Delete the album and its membership from the database
+	tx := database.GetDB().Begin()
+	err := tx.Where("album_id = ?", album.ID).Delete(models.AlbumPhoto{}).Error
+	if err == nil {
+		err = tx.Delete(&album).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to delete album", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to delete album", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
+
+// AddAlbumPhoto handles appending one of the user's photos to an album
+func AddAlbumPhoto(w http.ResponseWriter, r *http.Request) {
+	album, ok := findOwnedAlbum(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		PhotoID uint `json:"photo_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the photo exists and belongs to the album owner
+	var photo models.Photo
+	err = database.GetDB().First(&photo, req.PhotoID).Error
+	if err != nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+	if photo.UserID != album.UserID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var count int
+	database.GetDB().Model(&models.AlbumPhoto{}).Where("album_id = ? AND photo_id = ?", album.ID, photo.ID).Count(&count)
+	if count > 0 {
+		http.Error(w, "Photo is already in the album", http.StatusConflict)
+		return
+	}
+
+	// Append the photo after the current last position
+	var last struct{ Position *int }
+	database.GetDB().Model(&models.AlbumPhoto{}).Select("MAX(position) AS position").Where("album_id = ?", album.ID).Scan(&last)
+	position := 0
+	if last.Position != nil {
+		position = *last.Position + 1
+	}
+
+	membership := models.AlbumPhoto{AlbumID: album.ID, PhotoID: photo.ID, Position: position}
+	err = database.GetDB().Create(&membership).Error
+	if err != nil {
+		http.Error(w, "Failed to add photo to album", http.StatusInternalServerError)
+		return
+	}
+
+	loadAlbumPhotos(&album, album.UserID)
+
+	// Set appropriate response status and return the updated album
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(album)
+}
+
+// RemoveAlbumPhoto handles removing a photo from an album
+func RemoveAlbumPhoto(w http.ResponseWriter, r *http.Request) {
+	album, ok := findOwnedAlbum(w, r)
+	if !ok {
+		return
+	}
+
+	photoID, err := strconv.Atoi(mux.Vars(r)["photoID"])
+	if err != nil {
+		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.GetDB().Where("album_id = ? AND photo_id = ?", album.ID, photoID).Delete(models.AlbumPhoto{})
+	if result.Error != nil {
+		http.Error(w, "Failed to remove photo from album", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Photo not found in album", http.StatusNotFound)
+		return
+	}
+
+	// Clear the cover if it pointed at the removed photo
+	if album.CoverPhotoID != nil && *album.CoverPhotoID == uint(photoID) {
+		album.CoverPhotoID = nil
+		database.GetDB().Model(&album).Update("cover_photo_id", nil)
+	}
+
+	loadAlbumPhotos(&album, album.UserID)
+
+	// Set appropriate response status and return the updated album
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(album)
+}
+
+// ReorderAlbumPhotos handles setting a new order for all photos of an album
+func ReorderAlbumPhotos(w http.ResponseWriter, r *http.Request) {
+	album, ok := findOwnedAlbum(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		PhotoIDs []uint `json:"photo_ids"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// The new order must list every photo of the album exactly once
+	var memberships []models.AlbumPhoto
+	err = database.GetDB().Where("album_id = ?", album.ID).Find(&memberships).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch album photos", http.StatusInternalServerError)
+		return
+	}
+	photoIDs := uniqueIDs(req.PhotoIDs)
+	if len(photoIDs) != len(req.PhotoIDs) || len(photoIDs) != len(memberships) {
+		http.Error(w, "photo_ids must list every photo of the album once", http.StatusBadRequest)
+		return
+	}
+	for _, membership := range memberships {
+		if !containsID(photoIDs, membership.PhotoID) {
+			http.Error(w, "photo_ids must list every photo of the album once", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx := database.GetDB().Begin()
+	for i, photoID := range photoIDs {
+		err = tx.Model(&models.AlbumPhoto{}).Where("album_id = ? AND photo_id = ?", album.ID, photoID).
+			UpdateColumn("position", i).Error
+		if err != nil {
+			break
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to reorder album", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to reorder album", http.StatusInternalServerError)
+		return
+	}
+
+	loadAlbumPhotos(&album, album.UserID)
+
+	// Set appropriate response status and return the updated album
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(album)
+}
+
+// findAlbum loads the album from the route
+func findAlbum(w http.ResponseWriter, r *http.Request) (models.Album, bool) {
+	var album models.Album
+
+	albumID, err := strconv.Atoi(mux.Vars(r)["albumID"])
+	if err != nil {
+		http.Error(w, "Invalid album ID", http.StatusBadRequest)
+		return album, false
+	}
+
+	err = database.GetDB().First(&album, albumID).Error
+	if err != nil {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return album, false
+	}
+
+	return album, true
+}
+
+// findOwnedAlbum loads the album from the route and checks that the caller owns it
+func findOwnedAlbum(w http.ResponseWriter, r *http.Request) (models.Album, bool) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return models.Album{}, false
+	}
+
+	album, ok := findAlbum(w, r)
+	if !ok {
+		return album, false
+	}
+
+	// Check if the user is authorized to modify the album
+	if album.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return album, false
+	}
+
+	return album, true
+}
+
+// loadAlbumPhotos sets the album's photos that the viewer may see in their stored order
+func loadAlbumPhotos(album *models.Album, viewerID uint) {
+	condition, args := visiblePhotoCondition(viewerID)
+
+	var photos []models.Photo
+	database.GetDB().Joins("JOIN album_photos ON album_photos.photo_id = photos.id").
+		Where("album_photos.album_id = ?", album.ID).
+		Where(condition, args...).
+		Order("album_photos.position").Find(&photos)
+	attachVariants(photos)
+	attachMedia(photos)
+	markBookmarked(viewerID, photos)
+	album.Photos = photos
+}
+
+// ownsPhotos reports whether every photo exists and belongs to the user
+func ownsPhotos(userID uint, photoIDs []uint) bool {
+	if len(photoIDs) == 0 {
+		return true
+	}
+	var count int
+	database.GetDB().Model(&models.Photo{}).Where("id IN (?) AND user_id = ?", photoIDs, userID).Count(&count)
+	return count == len(photoIDs)
+}
+
+func normalizeAlbumVisibility(visibility string) (string, bool) {
+	switch visibility {
+	case "":
+		return models.AlbumPublic, true
+	case models.AlbumPublic, models.AlbumPrivate:
+		return visibility, true
+	default:
+		return "", false
+	}
+}
+
+// uniqueIDs returns ids without duplicates, keeping the first occurrence order
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	var unique []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+// BlockUser handles blocking another user. Follow relations in both directions are removed.
+func BlockUser(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	target, ok := findRouteUser(w, r)
+	if !ok {
+		return
+	}
+	if target.ID == userID {
+		http.Error(w, "You cannot block yourself", http.StatusBadRequest)
+		return
+	}
+
+	block := models.Block{BlockerID: userID, BlockedID: target.ID}
+
+	tx := database.GetDB().Begin()
+	err = tx.Where(models.Block{BlockerID: userID, BlockedID: target.ID}).FirstOrCreate(&block).Error
+	if err == nil {
+		err = tx.Where("(follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)",
+			userID, target.ID, target.ID, userID).Delete(models.Follow{}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the block
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(block)
+}
+
+// UnblockUser handles removing a block
+func UnblockUser(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	target, ok := findRouteUser(w, r)
+	if !ok {
+		return
+	}
+
+	result := database.GetDB().Where("blocker_id = ? AND blocked_id = ?", userID, target.ID).Delete(models.Block{})
+	if result.Error != nil {
+		http.Error(w, "Failed to unblock user", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "User is not blocked", http.StatusNotFound)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetBlocks handles fetching the users blocked by the logged-in user
+func GetBlocks(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var blocks []models.Block
+	err = database.GetDB().Where("blocker_id = ?", userID).Find(&blocks).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch blocked users", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched blocks
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(blocks)
+}
+
+// MuteUser handles muting another user
+func MuteUser(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	target, ok := findRouteUser(w, r)
+	if !ok {
+		return
+	}
+	if target.ID == userID {
+		http.Error(w, "You cannot mute yourself", http.StatusBadRequest)
+		return
+	}
+
+	mute := models.Mute{MuterID: userID, MutedID: target.ID}
+	err = database.GetDB().Where(models.Mute{MuterID: userID, MutedID: target.ID}).FirstOrCreate(&mute).Error
+	if err != nil {
+		http.Error(w, "Failed to mute user", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the mute
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mute)
+}
+
+// UnmuteUser handles removing a mute
+func UnmuteUser(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	target, ok := findRouteUser(w, r)
+	if !ok {
+		return
+	}
+
+	result := database.GetDB().Where("muter_id = ? AND muted_id = ?", userID, target.ID).Delete(models.Mute{})
+	if result.Error != nil {
+		http.Error(w, "Failed to unmute user", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "User is not muted", http.StatusNotFound)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetMutes handles fetching the users muted by the logged-in user
+func GetMutes(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var mutes []models.Mute
+	err = database.GetDB().Where("muter_id = ?", userID).Find(&mutes).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch muted users", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched mutes
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mutes)
+}
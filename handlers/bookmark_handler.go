@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/listing"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/gorilla/mux"
+)
+
+// BookmarkPhoto handles saving a photo the caller may see, optionally into one of
+// their collections. Bookmarking an already saved photo moves it to the given collection.
+func BookmarkPhoto(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	photoID, err := strconv.Atoi(mux.Vars(r)["photoID"])
+	if err != nil {
+		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
+		return
+	}
+
+	// The request body is optional
+	var req struct {
+		CollectionID *uint `json:"collection_id"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var photo models.Photo
+	err = database.GetDB().First(&photo, photoID).Error
+	if err != nil || !canViewPhoto(userID, photo) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+
+	if req.CollectionID != nil {
+		var collection models.BookmarkCollection
+		err = database.GetDB().Where("id = ? AND user_id = ?", *req.CollectionID, userID).First(&collection).Error
+		if err != nil {
+			http.Error(w, "Collection not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	var bookmark models.Bookmark
+	err = database.GetDB().Where(models.Bookmark{UserID: userID, PhotoID: photo.ID}).
+		Assign(map[string]interface{}{"collection_id": req.CollectionID}).
+		FirstOrCreate(&bookmark).Error
+	if err != nil {
+		http.Error(w, "Failed to bookmark photo", http.StatusInternalServerError)
+		return
+	}
+
+	attachVariant(&photo)
+
+	attachMediaItems(&photo)
+	photo.BookmarkedByMe = true
+	bookmark.Photo = photo
+
+	// Set appropriate response status and return the bookmark
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(bookmark)
+}
+
+// UnbookmarkPhoto handles removing a photo from the caller's bookmarks
+func UnbookmarkPhoto(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	photoID, err := strconv.Atoi(mux.Vars(r)["photoID"])
+	if err != nil {
+		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.GetDB().Where("user_id = ? AND photo_id = ?", userID, photoID).Delete(models.Bookmark{})
+	if result.Error != nil {
+		http.Error(w, "Failed to remove bookmark", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Photo is not bookmarked", http.StatusNotFound)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetBookmarks handles fetching the caller's bookmarks, newest first. Photos the
+// caller can no longer see are left out.
+func GetBookmarks(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query, err := listing.Parse(r.URL.Query(), bookmarkListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	condition, args := visiblePhotoCondition(userID)
+
+	var bookmarks []models.Bookmark
+	err = database.GetDB().Preload("Photo").Select("bookmarks.*").
+		Joins("JOIN photos ON photos.id = bookmarks.photo_id AND photos.deleted_at IS NULL").
+		Where("bookmarks.user_id = ?", userID).Where(condition, args...).
+		Scopes(query.Scope).Find(&bookmarks).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch bookmarks", http.StatusInternalServerError)
+		return
+	}
+	page := listing.Paginate(query, bookmarks)
+
+	photos := make([]models.Photo, len(page.Data))
+	for i, bookmark := range page.Data {
+		photos[i] = bookmark.Photo
+		photos[i].BookmarkedByMe = true
+	}
+	attachVariants(photos)
+	attachMedia(photos)
+	for i := range page.Data {
+		page.Data[i].Photo = photos[i]
+	}
+
+	// Set appropriate response status and return the fetched page of bookmarks
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// CreateBookmarkCollection handles creating a named collection for bookmarks
+func CreateBookmarkCollection(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		http.Error(w, "Collection name is required", http.StatusBadRequest)
+		return
+	}
+	if collectionNameTaken(userID, name, 0) {
+		http.Error(w, "Collection name already exists", http.StatusConflict)
+		return
+	}
+
+	collection := models.BookmarkCollection{UserID: userID, Name: name}
+	err = database.GetDB().Create(&collection).Error
+	if err != nil {
+		http.Error(w, "Failed to create collection", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the created collection
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// GetBookmarkCollections handles fetching the caller's bookmark collections
+func GetBookmarkCollections(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	collections := []models.BookmarkCollection{}
+	err = database.GetDB().Where("user_id = ?", userID).Order("name").Find(&collections).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch collections", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched collections
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(collections)
+}
+
+// UpdateBookmarkCollectionByID handles renaming a bookmark collection
+func UpdateBookmarkCollectionByID(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	collection, ok := findRouteCollection(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		http.Error(w, "Collection name is required", http.StatusBadRequest)
+		return
+	}
+	if collectionNameTaken(userID, name, collection.ID) {
+		http.Error(w, "Collection name already exists", http.StatusConflict)
+		return
+	}
+
+	collection.Name = name
+	err = database.GetDB().Save(&collection).Error
+	if err != nil {
+		http.Error(w, "Failed to update collection", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the updated collection
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// DeleteBookmarkCollectionByID handles deleting a bookmark collection. Its bookmarks
+// stay saved without a collection.
+func DeleteBookmarkCollectionByID(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	collection, ok := findRouteCollection(w, r, userID)
+	if !ok {
+		return
+	}
+
+	tx := database.GetDB().Begin()
+	err = tx.Model(&models.Bookmark{}).Where("collection_id = ?", collection.ID).Update("collection_id", nil).Error
+	if err == nil {
+		err = tx.Delete(&collection).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to delete collection", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to delete collection", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
+
+// findRouteCollection loads the caller's collection named by the collectionID route
+// variable, writing an error response when it cannot
+func findRouteCollection(w http.ResponseWriter, r *http.Request, userID uint) (models.BookmarkCollection, bool) {
+	var collection models.BookmarkCollection
+	collectionID, err := strconv.Atoi(mux.Vars(r)["collectionID"])
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return collection, false
+	}
+
+	err = database.GetDB().Where("id = ? AND user_id = ?", collectionID, userID).First(&collection).Error
+	if err != nil {
+		http.Error(w, "Collection not found", http.StatusNotFound)
+		return collection, false
+	}
+	return collection, true
+}
+
+// collectionNameTaken reports whether the user has another collection with the name
+func collectionNameTaken(userID uint, name string, exceptID uint) bool {
+	var count int
+	database.GetDB().Model(&models.BookmarkCollection{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, exceptID).Count(&count)
+	return count > 0
+}
+
+// markBookmarked sets BookmarkedByMe on the photos the viewer saved
+func markBookmarked(viewerID uint, photos []models.Photo) {
+	if viewerID == 0 || len(photos) == 0 {
+		return
+	}
+
+	photoIDs := make([]uint, len(photos))
+	for i, photo := range photos {
+		photoIDs[i] = photo.ID
+	}
+
+	var saved []uint
+	err := database.GetDB().Model(&models.Bookmark{}).
+		Where("user_id = ? AND photo_id IN (?)", viewerID, photoIDs).Pluck("photo_id", &saved).Error
+	if err != nil {
+		log.Printf("Failed to load bookmarks: %v", err)
+		return
+	}
+	for i := range photos {
+		photos[i].BookmarkedByMe = containsID(saved, photos[i].ID)
+	}
+}
+
+// markBookmarkedPhoto sets BookmarkedByMe on a single photo
+func markBookmarkedPhoto(viewerID uint, photo *models.Photo) {
+	photos := []models.Photo{*photo}
+	markBookmarked(viewerID, photos)
+	photo.BookmarkedByMe = photos[0].BookmarkedByMe
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/gorilla/mux"
+)
+
+const defaultMaxPinnedComments = 3
+
+// maxPinnedComments returns how many comments can be pinned on a photo, from PINNED_COMMENTS_MAX
+func maxPinnedComments() int {
+	if n, err := strconv.Atoi(os.Getenv("PINNED_COMMENTS_MAX")); err == nil && n > 0 {
+		return n
+	}
+	return defaultMaxPinnedComments
+}
+
+// UpdateCommentSettings handles changing who may comment on one of the caller's photos
+func UpdateCommentSettings(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	photo, ok := findOwnedRoutePhoto(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CommentPolicy string `json:"comment_policy"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	policy, ok := normalizeCommentPolicy(req.CommentPolicy)
+	if !ok {
+		http.Error(w, "Invalid comment policy", http.StatusBadRequest)
+		return
+	}
+
+	err = database.GetDB().Model(&photo).Update("comment_policy", policy).Error
+	if err != nil {
+		http.Error(w, "Failed to update comment settings", http.StatusInternalServerError)
+		return
+	}
+	photo.CommentPolicy = policy
+
+	respondWithUpdatedPhoto(w, photo, userID)
+}
+
+// PinComment handles pinning a comment on one of the caller's photos
+func PinComment(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	comment, ok := findPhotoOwnerComment(w, r, userID)
+	if !ok {
+		return
+	}
+	if comment.PinnedAt != nil {
+		http.Error(w, "Comment is already pinned", http.StatusConflict)
+		return
+	}
+	if comment.Hidden || comment.ShadowHidden {
+		http.Error(w, "Hidden comments cannot be pinned", http.StatusBadRequest)
+		return
+	}
+
+	var count int
+	database.GetDB().Model(&models.Comment{}).Where("photo_id = ? AND pinned_at IS NOT NULL", comment.PhotoID).Count(&count)
+	if count >= maxPinnedComments() {
+		http.Error(w, fmt.Sprintf("At most %d comments can be pinned", maxPinnedComments()), http.StatusConflict)
+		return
+	}
+
+	pinnedAt := time.Now()
+	err = database.GetDB().Model(&comment).UpdateColumn("pinned_at", pinnedAt).Error
+	if err != nil {
+		http.Error(w, "Failed to pin comment", http.StatusInternalServerError)
+		return
+	}
+	comment.PinnedAt = &pinnedAt
+	attachCommentReactions(userID, &comment)
+
+	// Set appropriate response status and return the pinned comment
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// UnpinComment handles unpinning a comment on one of the caller's photos
+func UnpinComment(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	comment, ok := findPhotoOwnerComment(w, r, userID)
+	if !ok {
+		return
+	}
+	if comment.PinnedAt == nil {
+		http.Error(w, "Comment is not pinned", http.StatusNotFound)
+		return
+	}
+
+	err = database.GetDB().Model(&comment).UpdateColumn("pinned_at", nil).Error
+	if err != nil {
+		http.Error(w, "Failed to unpin comment", http.StatusInternalServerError)
+		return
+	}
+	comment.PinnedAt = nil
+	attachCommentReactions(userID, &comment)
+
+	// Set appropriate response status and return the unpinned comment
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// findPhotoOwnerComment loads the comment referenced by the commentID route variable
+// and checks that the user owns the photo it was left on
+func findPhotoOwnerComment(w http.ResponseWriter, r *http.Request, userID uint) (models.Comment, bool) {
+	var comment models.Comment
+
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentID"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return comment, false
+	}
+
+	err = database.GetDB().Preload("Photo").First(&comment, commentID).Error
+	if err != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return comment, false
+	}
+	if comment.Photo.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return comment, false
+	}
+
+	return comment, true
+}
+
+// normalizeCommentPolicy validates a comment policy, defaulting to everyone
+func normalizeCommentPolicy(policy string) (string, bool) {
+	switch policy {
+	case "":
+		return models.CommentsEveryone, true
+	case models.CommentsEveryone, models.CommentsFollowers, models.CommentsOff:
+		return policy, true
+	default:
+		return "", false
+	}
+}
+
+// canCommentOn reports whether the user may comment on the photo under its comment
+// policy. Photo owners may always comment on their own photos.
+func canCommentOn(userID uint, photo models.Photo) bool {
+	if photo.UserID == userID {
+		return true
+	}
+	switch photo.CommentPolicy {
+	case models.CommentsOff:
+		return false
+	case models.CommentsFollowers:
+		return isFollowing(userID, photo.UserID)
+	default:
+		return true
+	}
+}
+
+// attachPinnedComments loads the pinned comments of a photo that the viewer may see,
+// in the order they were pinned
+func attachPinnedComments(viewerID uint, photo *models.Photo) {
+	condition, args := visibleCommentCondition(viewerID)
+
+	var pinned []models.Comment
+	database.GetDB().Where("comments.photo_id = ? AND comments.pinned_at IS NOT NULL", photo.ID).
+		Where(condition, args...).Order("comments.pinned_at").Find(&pinned)
+	attachReactions(viewerID, pinned)
+	photo.PinnedComments = pinned
+}
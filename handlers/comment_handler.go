@@ -2,18 +2,24 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/listing"
 	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/search"
+	"github.com/faazabilamri7/mygram/spam"
+	"github.com/faazabilamri7/mygram/trash"
+	"github.com/faazabilamri7/mygram/webhooks"
 	"github.com/gorilla/mux"
 )
 
 // CreateComment handles the creation of a new comment
 func CreateComment(w http.ResponseWriter, r *http.Request) {
-	var comment models.Comment
-	err := json.NewDecoder(r.Body).Decode(&comment)
+	comment, err := decodeNewComment(r.Body)
 	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
@@ -26,39 +32,133 @@ func CreateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Users in a spam cooldown cannot comment until it ends
+	author, ok := checkCooldown(w, userID)
+	if !ok {
+		return
+	}
+
 	// Set user ID for the comment
 	comment.UserID = userID
 
-	// Save comment to the database
-	err = database.GetDB().Create(&comment).Error
+	// Comments can only be added to photos the user can see
+	var photo models.Photo
+	err = database.GetDB().First(&photo, comment.PhotoID).Error
+	if err != nil || !canViewPhoto(userID, photo) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+
+	// Photo owners decide who may comment
+	if !canCommentOn(userID, photo) {
+		http.Error(w, "You cannot comment on this photo", http.StatusForbidden)
+		return
+	}
+
+	// Blocked users cannot be mentioned
+	if mentionsBlockedUser(userID, comment.Message) {
+		http.Error(w, "You cannot mention one or more of these users", http.StatusForbidden)
+		return
+	}
+
+	held, ok := filterContent(w, &comment.Message)
+	if !ok {
+		return
+	}
+
+	// Score the comment for spam from its author's account and recent activity
+	spamScore, err := spam.AssessComment(author, comment)
+	if err != nil {
+		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+		return
+	}
+
+	// Held comments and comments using words the photo owner hid are only visible to
+	// their author. Likely spam is too, without telling the author.
+	comment.Hidden = len(held) > 0 || containsHiddenWord(photo, userID, comment.Message)
+	comment.ShadowHidden = spamScore.Action != models.SpamAllow
+
+	// Save comment, its spam score and the report of a held comment to the database
+	tx := database.GetDB().Begin()
+	err = tx.Create(&comment).Error
+	if err == nil {
+		spamScore.TargetID = comment.ID
+		err = tx.Create(&spamScore).Error
+	}
+	if err == nil && spamScore.Action == models.SpamCooldown {
+		err = tx.Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumn("cooldown_until", time.Now().Add(spam.CooldownDuration())).Error
+	}
+	if err == nil && len(held) > 0 {
+		err = holdForReview(tx, models.ReportComment, comment.ID, held)
+	}
 	if err != nil {
+		tx.Rollback()
 		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
 		return
 	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+		return
+	}
+
+	attachCommentReactions(userID, &comment)
+
+	// Notify webhook subscribers of the comment author and the photo owner
+	webhooks.Dispatch(webhooks.CommentCreated, comment, commentAudience(comment)...)
+	indexComment(comment)
 
 	// Set appropriate response status and return the created comment
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(comment)
 }
 
-// GetAllComments handles fetching all comments from all users
+// decodeNewComment reads the fields a client may set on a new comment. Everything
+// else, the timestamps in particular, is left for the server so a backdated comment
+// cannot slip out of the spam checks' time windows.
+func decodeNewComment(body io.Reader) (models.Comment, error) {
+	var req struct {
+		PhotoID uint   `json:"photo_id"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return models.Comment{}, err
+	}
+	return models.Comment{PhotoID: req.PhotoID, Message: req.Message}, nil
+}
+
+// GetAllComments handles fetching all comments from all users on photos the caller may see
 func GetAllComments(w http.ResponseWriter, r *http.Request) {
+	query, err := listing.Parse(r.URL.Query(), commentListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewerID := getOptionalUserID(r)
+	condition, args := visibleCommentCondition(viewerID)
+	notMuted, mutedArgs := notMutedCondition("comments.user_id", viewerID)
+
 	var comments []models.Comment
-	err := database.GetDB().Preload("User").Preload("Photo").Find(&comments).Error
+	err = database.GetDB().Preload("User").Preload("Photo").Where(condition, args...).Where(notMuted, mutedArgs...).
+		Scopes(query.Scope).Find(&comments).Error
 	if err != nil {
 		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
 		return
 	}
 
-	// Set appropriate response status and return the fetched comments
+	page := listing.Paginate(query, comments)
+	attachReactions(viewerID, page.Data)
+
+	// Set appropriate response status and return the fetched page of comments
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(comments)
+	json.NewEncoder(w).Encode(page)
 }
 
 // GetCommentByID handles fetching a comment by its ID
 func GetCommentByID(w http.ResponseWriter, r *http.Request) {
-	commentIDStr := mux.Vars(r)["commentId"]
+	commentIDStr := mux.Vars(r)["commentID"]
 	commentID, err := strconv.Atoi(commentIDStr)
 	if err != nil {
 		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
@@ -72,6 +172,14 @@ func GetCommentByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Comments on hidden photos or by hidden accounts are reported as missing
+	viewerID := getOptionalUserID(r)
+	if !canViewComment(viewerID, comment) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	attachCommentReactions(viewerID, &comment)
+
 	// Set appropriate response status and return the fetched comment
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -87,7 +195,7 @@ func UpdateCommentByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	commentIDStr := mux.Vars(r)["commentId"]
+	commentIDStr := mux.Vars(r)["commentID"]
 	commentID, err := strconv.Atoi(commentIDStr)
 	if err != nil {
 		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
@@ -115,22 +223,97 @@ func UpdateCommentByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Users in a spam cooldown cannot edit their comments until it ends
+	author, ok := checkCooldown(w, userID)
+	if !ok {
+		return
+	}
+
+	// Blocked users cannot be mentioned
+	if mentionsBlockedUser(userID, updatedComment.Message) {
+		http.Error(w, "You cannot mention one or more of these users", http.StatusForbidden)
+		return
+	}
+
+	// Edits go through the content filter; a held edit hides the comment until reviewed.
+	// Otherwise, unless moderators hid it, the photo owner's hidden words decide again.
+	held, ok := filterContent(w, &updatedComment.Message)
+	if !ok {
+		return
+	}
+	if len(held) > 0 {
+		existingComment.Hidden = true
+	} else if !existingComment.Hidden || !hiddenByModeration(models.ReportComment, existingComment.ID) {
+		var photo models.Photo
+		database.GetDB().Select("id, user_id").First(&photo, existingComment.PhotoID)
+		existingComment.Hidden = containsHiddenWord(photo, userID, updatedComment.Message)
+	}
+
+	// Keep the previous message as a revision and score the new one for spam. A
+	// shadow-hidden comment stays hidden until a moderator approves it.
+	var revision *models.CommentRevision
+	var spamScore *models.SpamScore
+	if existingComment.Message != updatedComment.Message {
+		revision = &models.CommentRevision{CommentID: existingComment.ID, Message: existingComment.Message}
+		editedAt := time.Now()
+		existingComment.Edited = true
+		existingComment.EditedAt = &editedAt
+
+		edited := existingComment
+		edited.Message = updatedComment.Message
+		score, err := spam.AssessComment(author, edited)
+		if err != nil {
+			http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+			return
+		}
+		score.TargetID = existingComment.ID
+		spamScore = &score
+		if score.Action != models.SpamAllow {
+			existingComment.ShadowHidden = true
+		}
+	}
+
 	// Update comment message
 	existingComment.Message = updatedComment.Message
 
-	// Save updated comment to the database
-	err = database.GetDB().Save(&existingComment).Error
+	// Save updated comment, its revision and spam score to the database
+	tx := database.GetDB().Begin()
+	err = tx.Save(&existingComment).Error
+	if err == nil && revision != nil {
+		err = tx.Create(revision).Error
+	}
+	if err == nil && spamScore != nil {
+		err = tx.Create(spamScore).Error
+	}
+	if err == nil && spamScore != nil && spamScore.Action == models.SpamCooldown {
+		err = tx.Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumn("cooldown_until", time.Now().Add(spam.CooldownDuration())).Error
+	}
+	if err == nil && len(held) > 0 {
+		err = holdForReview(tx, models.ReportComment, existingComment.ID, held)
+	}
 	if err != nil {
+		tx.Rollback()
 		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
 		return
 	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+
+	attachCommentReactions(userID, &existingComment)
+
+	// Notify webhook subscribers of the comment author and the photo owner
+	webhooks.Dispatch(webhooks.CommentUpdated, existingComment, commentAudience(existingComment)...)
+	indexComment(existingComment)
 
 	// Set appropriate response status and return the updated comment
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(existingComment)
 }
 
-// DeleteCommentByID handles deleting a comment by its ID
+// DeleteCommentByID handles deleting a comment by its ID, by its author or by the owner of the photo
 func DeleteCommentByID(w http.ResponseWriter, r *http.Request) {
 	// Retrieve user ID from token or request context
 	userID, err := getUserIDFromToken(r)
@@ -139,7 +322,7 @@ func DeleteCommentByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	commentIDStr := mux.Vars(r)["commentId"]
+	commentIDStr := mux.Vars(r)["commentID"]
 	commentID, err := strconv.Atoi(commentIDStr)
 	if err != nil {
 		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
@@ -154,19 +337,59 @@ func DeleteCommentByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if the user is authorized to delete the comment
-	if existingComment.UserID != userID {
+	// Check if the user is authorized to delete the comment: its author or the photo owner
+	var photo models.Photo
+	database.GetDB().Select("id, user_id").First(&photo, existingComment.PhotoID)
+	if existingComment.UserID != userID && photo.UserID != userID {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Delete the comment from the database
-	err = database.GetDB().Delete(&existingComment).Error
+	if existingComment.UserID == userID {
+		// Move the comment to the trash; it is purged after the retention window
+		err = database.GetDB().Delete(&existingComment).Error
+	} else {
+		// Comments removed by the photo owner are purged so the author cannot restore them
+		err = trash.PurgeComment(existingComment)
+	}
 	if err != nil {
 		http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
 		return
 	}
 
+	// Notify webhook subscribers of the comment author and the photo owner
+	webhooks.Dispatch(webhooks.CommentDeleted, existingComment, commentAudience(existingComment)...)
+	search.Remove(search.TypeComment, existingComment.ID)
+
 	// Set appropriate response status
 	w.WriteHeader(http.StatusOK)
 }
+
+// commentAudience returns the users whose webhooks receive events about a comment.
+// Photo owners are not notified about hidden comments or comments from users they
+// muted or blocked.
+func commentAudience(comment models.Comment) []uint {
+	userIDs := []uint{comment.UserID}
+	if comment.Hidden || comment.ShadowHidden {
+		return userIDs
+	}
+
+	var photo models.Photo
+	err := database.GetDB().Select("id, user_id").First(&photo, comment.PhotoID).Error
+	if err == nil && photo.UserID != comment.UserID &&
+		!hasMuted(photo.UserID, comment.UserID) && !isBlockedBetween(photo.UserID, comment.UserID) {
+		userIDs = append(userIDs, photo.UserID)
+	}
+
+	return userIDs
+}
+
+// indexComment adds a comment to the search index, or removes it while it is hidden
+// by a moderator, the content filter or as spam
+func indexComment(comment models.Comment) {
+	if comment.Hidden || comment.ShadowHidden {
+		search.Remove(search.TypeComment, comment.ID)
+		return
+	}
+	search.IndexComment(comment)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeNewComment(t *testing.T) {
+	body := `{"id":7,"photo_id":3,"message":"hi","user_id":9,"hidden":false,"edited":true,
+		"pinned_at":"2000-01-01T00:00:00Z","created_at":"2000-01-01T00:00:00Z",
+		"updated_at":"2000-01-01T00:00:00Z","deleted_at":"2000-01-01T00:00:00Z"}`
+	comment, err := decodeNewComment(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.PhotoID != 3 || comment.Message != "hi" {
+		t.Errorf("lost the client fields: %+v", comment)
+	}
+
+	// A zero CreatedAt is stamped by gorm on insert, so a backdated comment still
+	// falls inside the spam velocity and duplicate windows
+	if !comment.CreatedAt.IsZero() || !comment.UpdatedAt.IsZero() {
+		t.Errorf("kept client timestamps %v, %v", comment.CreatedAt, comment.UpdatedAt)
+	}
+	// A client-chosen ID would also exclude an earlier comment from those counts
+	if comment.ID != 0 || comment.UserID != 0 || comment.Edited || comment.PinnedAt != nil || comment.DeletedAt != nil {
+		t.Errorf("kept server fields: %+v", comment)
+	}
+
+	if _, err := decodeNewComment(strings.NewReader("{")); err == nil {
+		t.Error("expected an error for a malformed body")
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/faazabilamri7/mygram/contentfilter"
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/jinzhu/gorm"
+)
+
+// filterContent runs the texts through the content filter and replaces masked words
+// in place. Rejected content gets an error response and ok is false; otherwise the
+// reasons are returned when the content has to be held for review.
+func filterContent(w http.ResponseWriter, texts ...*string) (held []string, ok bool) {
+	var rejected []string
+	for _, text := range texts {
+		verdict := contentfilter.GetFilter().Check(*text)
+		switch verdict.Action {
+		case contentfilter.Reject:
+			rejected = append(rejected, verdict.Reasons...)
+		case contentfilter.Hold:
+			held = append(held, verdict.Reasons...)
+		}
+		*text = verdict.Text
+	}
+
+	if len(rejected) > 0 {
+		http.Error(w, "Content not allowed: "+strings.Join(rejected, ", "), http.StatusBadRequest)
+		return nil, false
+	}
+	return held, true
+}
+
+// holdForReview files a report for content the filter held, so that moderators can
+// approve or remove it
+func holdForReview(tx *gorm.DB, targetType string, targetID uint, reasons []string) error {
+	return tx.Create(&models.Report{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     models.ReasonContentFilter,
+		Details:    strings.Join(reasons, ", "),
+		Status:     models.ReportOpen,
+	}).Error
+}
+
+// containsHiddenWord reports whether a comment message uses one of the words the photo
+// owner hid. Owners' own comments are never hidden.
+func containsHiddenWord(photo models.Photo, authorID uint, message string) bool {
+	if photo.UserID == authorID {
+		return false
+	}
+
+	var words []string
+	database.GetDB().Model(&models.HiddenWord{}).Where("user_id = ?", photo.UserID).Pluck("word", &words)
+	return contentfilter.ContainsWord(message, words)
+}
+
+// hiddenByModeration reports whether hidden content stays hidden for moderators: the
+// filter held it and the report is still open, or a moderator's latest decision on it
+// was to hide it
+func hiddenByModeration(targetType string, targetID uint) bool {
+	var held int
+	database.GetDB().Model(&models.Report{}).
+		Where("target_type = ? AND target_id = ? AND reason = ? AND status IN (?)", targetType, targetID,
+			models.ReasonContentFilter, []string{models.ReportOpen, models.ReportInReview}).
+		Count(&held)
+	if held > 0 {
+		return true
+	}
+
+	var decision models.ModerationAction
+	err := database.GetDB().Where("target_type = ? AND target_id = ? AND action IN (?)", targetType, targetID,
+		[]string{models.ActionHide, models.ActionApprove}).Order("id DESC").First(&decision).Error
+	return err == nil && decision.Action == models.ActionHide
+}
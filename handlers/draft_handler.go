@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/listing"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/publishing"
+)
+
+// GetDrafts handles fetching the caller's draft and scheduled photos. Drafts are
+// edited with PUT /photos/{photoID} and discarded with DELETE /photos/{photoID}.
+func GetDrafts(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query, err := listing.Parse(r.URL.Query(), draftListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var photos []models.Photo
+	err = database.GetDB().Where("photos.user_id = ? AND photos.status IN (?)", userID,
+		[]string{models.PhotoDraft, models.PhotoScheduled}).
+		Scopes(query.Scope).Find(&photos).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch drafts", http.StatusInternalServerError)
+		return
+	}
+	page := listing.Paginate(query, photos)
+	attachVariants(page.Data)
+	attachMedia(page.Data)
+
+	// Set appropriate response status and return the fetched page of drafts
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// PublishPhoto handles publishing a draft or scheduled photo immediately
+func PublishPhoto(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	photo, ok := findOwnedRoutePhoto(w, r, userID)
+	if !ok {
+		return
+	}
+
+	photo, err = publishing.Publish(photo)
+	if err == publishing.ErrAlreadyPublished {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to publish photo", http.StatusInternalServerError)
+		return
+	}
+	attachVariant(&photo)
+	attachMediaItems(&photo)
+
+	// Set appropriate response status and return the published photo
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(photo)
+}
+
+// CancelScheduledPhoto handles cancelling the scheduled publication of a photo,
+// which turns it back into a draft
+func CancelScheduledPhoto(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	photo, ok := findOwnedRoutePhoto(w, r, userID)
+	if !ok {
+		return
+	}
+
+	result := database.GetDB().Model(&models.Photo{}).Where("id = ? AND status = ?", photo.ID, models.PhotoScheduled).
+		Updates(map[string]interface{}{"status": models.PhotoDraft, "publish_at": nil})
+	if result.Error != nil {
+		http.Error(w, "Failed to cancel scheduled photo", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Photo is not scheduled", http.StatusConflict)
+		return
+	}
+	photo.Status = models.PhotoDraft
+	photo.PublishAt = nil
+	attachVariant(&photo)
+	attachMediaItems(&photo)
+
+	// Set appropriate response status and return the draft
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(photo)
+}
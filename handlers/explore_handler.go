@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/listing"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/trending"
+)
+
+// ExplorePhoto is a trending photo with its engagement within the window
+type ExplorePhoto struct {
+	models.Photo
+	Likes    int     `json:"likes"`
+	Comments int     `json:"comments"`
+	Score    float64 `json:"score"`
+}
+
+// trendingWindow reads the window query parameter, defaulting to the 24 hour window
+func trendingWindow(r *http.Request) (string, bool) {
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		return trending.DefaultWindow, true
+	}
+	_, ok := trending.Windows[window]
+	return window, ok
+}
+
+// GetExplore handles GET /explore, listing the highest scoring public photos of the
+// window. Photos of blocked and muted users are left out.
+func GetExplore(w http.ResponseWriter, r *http.Request) {
+	window, ok := trendingWindow(r)
+	if !ok {
+		http.Error(w, "Invalid window", http.StatusBadRequest)
+		return
+	}
+
+	values := r.URL.Query()
+	limit, err := listing.ParseLimit(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The explore cursor is the offset into the ranked photos
+	offset, err := listing.ParseOffsetCursor(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewerID := getOptionalUserID(r)
+	condition, args := visiblePhotoCondition(viewerID)
+	notMuted, mutedArgs := notMutedCondition("photos.user_id", viewerID)
+
+	var scores []models.PhotoScore
+	err = database.GetDB().Table("photo_scores").Select("photo_scores.*").
+		Joins("JOIN photos ON photos.id = photo_scores.photo_id AND photos.deleted_at IS NULL").
+		Where("photo_scores.time_window = ?", window).
+		Where(condition, args...).Where(notMuted, mutedArgs...).
+		Order("photo_scores.score DESC, photo_scores.photo_id DESC").
+		Offset(offset).Limit(limit + 1).Find(&scores).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch explore photos", http.StatusInternalServerError)
+		return
+	}
+
+	page := listing.Page[ExplorePhoto]{Data: []ExplorePhoto{}}
+	if len(scores) > limit {
+		scores = scores[:limit]
+		page.NextCursor = listing.OffsetCursor(offset + limit)
+	}
+
+	ids := make([]uint, len(scores))
+	for i, score := range scores {
+		ids[i] = score.PhotoID
+	}
+	var photos []models.Photo
+	if len(ids) > 0 {
+		err = database.GetDB().Preload("User").Where("id IN (?)", ids).Find(&photos).Error
+		if err != nil {
+			http.Error(w, "Failed to fetch explore photos", http.StatusInternalServerError)
+			return
+		}
+	}
+	attachVariants(photos)
+	attachMedia(photos)
+	markBookmarked(viewerID, photos)
+
+	// Keep the photos in rank order
+	byID := map[uint]models.Photo{}
+	for _, photo := range photos {
+		byID[photo.ID] = photo
+	}
+	for _, score := range scores {
+		if photo, ok := byID[score.PhotoID]; ok {
+			page.Data = append(page.Data, ExplorePhoto{Photo: photo, Likes: score.Likes, Comments: score.Comments, Score: score.Score})
+		}
+	}
+
+	// Set appropriate response status and return the fetched page of photos
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetTrendingTags handles GET /tags/trending, ranking hashtags by the summed scores
+// of the trending photos that use them
+func GetTrendingTags(w http.ResponseWriter, r *http.Request) {
+	window, ok := trendingWindow(r)
+	if !ok {
+		http.Error(w, "Invalid window", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := listing.ParseLimit(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Only photos the viewer may see count, checked on the photos themselves since
+	// the scores are a snapshot of the last aggregation
+	viewerID := getOptionalUserID(r)
+	condition, args := visiblePhotoCondition(viewerID)
+	notMuted, mutedArgs := notMutedCondition("photos.user_id", viewerID)
+	query := database.GetDB().Table("photo_tags").
+		Select("photo_tags.tag, SUM(photo_scores.score) AS score, COUNT(*) AS photo_count").
+		Joins("JOIN photo_scores ON photo_scores.photo_id = photo_tags.photo_id").
+		Joins("JOIN photos ON photos.id = photo_tags.photo_id AND photos.deleted_at IS NULL").
+		Where("photo_scores.time_window = ?", window).
+		Where(condition, args...).Where(notMuted, mutedArgs...)
+
+	tags := []models.TrendingTag{}
+	err = query.Group("photo_tags.tag").Order("score DESC, photo_tags.tag").Limit(limit).Scan(&tags).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch trending tags", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the trending tags
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tags)
+}
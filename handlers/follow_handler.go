@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/gorilla/mux"
+)
+
+// FollowUser handles following another user. Following a private account creates a
+// pending follow request that the account owner has to approve.
+func FollowUser(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	target, ok := findRouteUser(w, r)
+	if !ok {
+		return
+	}
+	if target.ID == userID {
+		http.Error(w, "You cannot follow yourself", http.StatusBadRequest)
+		return
+	}
+
+	// Blocked users cannot follow each other
+	if isBlockedBetween(userID, target.ID) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// Following twice keeps the existing relation
+	var follow models.Follow
+	err = database.GetDB().Where("follower_id = ? AND followee_id = ?", userID, target.ID).First(&follow).Error
+	if err == nil {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(follow)
+		return
+	}
+
+	follow = models.Follow{
+		FollowerID: userID,
+		FolloweeID: target.ID,
+		Status:     models.FollowAccepted,
+	}
+	if target.IsPrivate {
+		follow.Status = models.FollowPending
+	}
+	err = database.GetDB().Create(&follow).Error
+	if err != nil {
+		http.Error(w, "Failed to follow user", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the follow relation
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(follow)
+}
+
+// UnfollowUser handles unfollowing a user or cancelling a pending follow request
+func UnfollowUser(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	targetID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.GetDB().Where("follower_id = ? AND followee_id = ?", userID, targetID).Delete(models.Follow{})
+	if result.Error != nil {
+		http.Error(w, "Failed to unfollow user", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "You are not following this user", http.StatusNotFound)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetFollowers handles fetching the approved followers of a user
+func GetFollowers(w http.ResponseWriter, r *http.Request) {
+	target, ok := findVisibleRouteUser(w, r)
+	if !ok {
+		return
+	}
+
+	var users []models.User
+	err := database.GetDB().
+		Where("id IN (SELECT follower_id FROM follows WHERE followee_id = ? AND status = ?)", target.ID, models.FollowAccepted).
+		Find(&users).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch followers", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched users
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(publicUsers(users))
+}
+
+// GetFollowing handles fetching the users a user follows
+func GetFollowing(w http.ResponseWriter, r *http.Request) {
+	target, ok := findVisibleRouteUser(w, r)
+	if !ok {
+		return
+	}
+
+	var users []models.User
+	err := database.GetDB().
+		Where("id IN (SELECT followee_id FROM follows WHERE follower_id = ? AND status = ?)", target.ID, models.FollowAccepted).
+		Find(&users).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch following", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched users
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(publicUsers(users))
+}
+
+// findRouteUser loads the user referenced by the userID route variable
+func findRouteUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	var user models.User
+
+	userID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return user, false
+	}
+
+	err = database.GetDB().First(&user, userID).Error
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return user, false
+	}
+
+	return user, true
+}
+
+// GetFollowRequests handles fetching the pending follow requests of the logged-in user
+func GetFollowRequests(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var requests []models.Follow
+	err = database.GetDB().Where("followee_id = ? AND status = ?", userID, models.FollowPending).
+		Order("created_at").Find(&requests).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch follow requests", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched requests
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(requests)
+}
+
+// ApproveFollowRequest handles approving a pending follow request
+func ApproveFollowRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := findPendingFollowRequest(w, r)
+	if !ok {
+		return
+	}
+
+	request.Status = models.FollowAccepted
+	err := database.GetDB().Save(&request).Error
+	if err != nil {
+		http.Error(w, "Failed to approve follow request", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the approved follow
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(request)
+}
+
+// DenyFollowRequest handles denying a pending follow request
+func DenyFollowRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := findPendingFollowRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := database.GetDB().Delete(&request).Error
+	if err != nil {
+		http.Error(w, "Failed to deny follow request", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
+
+// RemoveFollower handles removing an approved follower from the logged-in user
+func RemoveFollower(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	followerID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.GetDB().Where("follower_id = ? AND followee_id = ?", followerID, userID).Delete(models.Follow{})
+	if result.Error != nil {
+		http.Error(w, "Failed to remove follower", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Follower not found", http.StatusNotFound)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
+
+// findPendingFollowRequest loads a pending follow request addressed to the caller
+func findPendingFollowRequest(w http.ResponseWriter, r *http.Request) (models.Follow, bool) {
+	var request models.Follow
+
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return request, false
+	}
+
+	requestID, err := strconv.Atoi(mux.Vars(r)["requestID"])
+	if err != nil {
+		http.Error(w, "Invalid follow request ID", http.StatusBadRequest)
+		return request, false
+	}
+
+	err = database.GetDB().Where("id = ? AND followee_id = ? AND status = ?", requestID, userID, models.FollowPending).
+		First(&request).Error
+	if err != nil {
+		http.Error(w, "Follow request not found", http.StatusNotFound)
+		return request, false
+	}
+
+	return request, true
+}
+
+// findVisibleRouteUser loads the user from the route and hides private accounts from non-followers
+func findVisibleRouteUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	user, ok := findRouteUser(w, r)
+	if !ok {
+		return user, false
+	}
+
+	if !canViewUserContent(getOptionalUserID(r), user.ID) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return user, false
+	}
+
+	return user, true
+}
+
+// publicUsers strips private fields before users are returned to other users. Account
+// settings and moderation state are only for the user and moderators to see.
+func publicUsers(users []models.User) []models.User {
+	for i := range users {
+		users[i].Password = ""
+		users[i].Email = ""
+		users[i].KeepPhotoMetadata = false
+		users[i].IsPrivate = false
+		users[i].Role = ""
+		users[i].WarningCount = 0
+		users[i].SuspendedUntil = nil
+		users[i].Verified = false
+		users[i].CooldownUntil = nil
+	}
+	return users
+}
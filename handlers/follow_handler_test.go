@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/faazabilamri7/mygram/models"
+)
+
+func TestPublicUsers(t *testing.T) {
+	now := time.Now()
+	users := publicUsers([]models.User{{
+		ID:                4,
+		Username:          "alice",
+		Email:             "alice@example.com",
+		Password:          "hash",
+		Age:               30,
+		ImageURL:          "https://img.example/alice.jpg",
+		KeepPhotoMetadata: true,
+		IsPrivate:         true,
+		Role:              models.RoleModerator,
+		WarningCount:      2,
+		SuspendedUntil:    &now,
+		Verified:          true,
+		CooldownUntil:     &now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}})
+
+	want := models.User{
+		ID:        4,
+		Username:  "alice",
+		Age:       30,
+		ImageURL:  "https://img.example/alice.jpg",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if !reflect.DeepEqual(users[0], want) {
+		t.Errorf("got %+v\nwant %+v", users[0], want)
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+// hashtagPattern matches #tags made of letters, digits and underscores
+var hashtagPattern = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+
+// extractHashtags returns the distinct lowercase hashtags used in the texts
+func extractHashtags(texts ...string) []string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, text := range texts {
+		for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+			tag := strings.ToLower(match[1])
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// syncPhotoTags replaces the hashtags stored for a photo with those in its title and caption
+func syncPhotoTags(photo models.Photo) error {
+	tx := database.GetDB().Begin()
+	err := tx.Where("photo_id = ?", photo.ID).Delete(models.PhotoTag{}).Error
+	for _, tag := range extractHashtags(photo.Title, photo.Caption) {
+		if err != nil {
+			break
+		}
+		err = tx.Create(&models.PhotoTag{PhotoID: photo.ID, Tag: tag}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/gorilla/mux"
+)
+
+const maxHiddenWordLength = 100
+
+// GetHiddenWords handles fetching the words the logged-in user hides from comments on their photos
+func GetHiddenWords(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var words []models.HiddenWord
+	err = database.GetDB().Where("user_id = ?", userID).Order("word").Find(&words).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch hidden words", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched hidden words
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(words)
+}
+
+// AddHiddenWord handles adding a word to hide. New comments on the user's photos that
+// contain it are only visible to their author.
+func AddHiddenWord(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Word string `json:"word"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	word := strings.ToLower(strings.TrimSpace(req.Word))
+	if word == "" {
+		http.Error(w, "Word is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(word) > maxHiddenWordLength {
+		http.Error(w, "Word is too long", http.StatusBadRequest)
+		return
+	}
+
+	hidden := models.HiddenWord{UserID: userID, Word: word}
+	err = database.GetDB().Where(models.HiddenWord{UserID: userID, Word: word}).FirstOrCreate(&hidden).Error
+	if err != nil {
+		http.Error(w, "Failed to add hidden word", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the hidden word
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hidden)
+}
+
+// DeleteHiddenWord handles removing one of the logged-in user's hidden words
+func DeleteHiddenWord(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	wordID, err := strconv.Atoi(mux.Vars(r)["wordID"])
+	if err != nil {
+		http.Error(w, "Invalid hidden word ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.GetDB().Where("id = ? AND user_id = ?", wordID, userID).Delete(models.HiddenWord{})
+	if result.Error != nil {
+		http.Error(w, "Failed to delete hidden word", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Hidden word not found", http.StatusNotFound)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
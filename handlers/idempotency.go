@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/faazabilamri7/mygram/idempotency"
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotentBodyBytes bounds the buffered body of requests without uploads
+	maxIdempotentBodyBytes = 1 << 20
+)
+
+// uploadRoutes maps the POST routes taking multipart uploads to how many files
+// they accept
+var uploadRoutes = map[string]func() int{
+	"/photos":  maxMediaItems,
+	"/stories": func() int { return 1 },
+}
+
+// Idempotency is a middleware making POST requests with an Idempotency-Key header safe
+// to retry. The first response per user and key is stored and replayed for retries
+// of the same request; reusing the key for a different request is rejected.
+func Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		// Keys are scoped to the user, so anonymous requests are handled as usual
+		userID, err := parseTokenUserID(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// The body is buffered to be hashed, up to the largest body the route accepts
+		r.Body = http.MaxBytesReader(w, r.Body, bodyLimit(r))
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash, err := requestHash(r, body)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		record, completed, err := idempotency.Begin(userID, key, hash)
+		switch err {
+		case nil:
+		case idempotency.ErrMismatch:
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case idempotency.ErrInProgress:
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		default:
+			http.Error(w, "Failed to process Idempotency-Key", http.StatusInternalServerError)
+			return
+		}
+
+		// Replay the stored response of the first request
+		if completed {
+			for name, value := range map[string]string{
+				"Content-Type": record.ContentType,
+				"Location":     record.Location,
+				"Retry-After":  record.RetryAfter,
+			} {
+				if value != "" {
+					w.Header().Set(name, value)
+				}
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.StatusCode)
+			io.WriteString(w, record.Body)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		// Server errors, conflicts and rate limiting are not stored so that the client can retry
+		if isRetryableStatus(recorder.status) {
+			err = idempotency.Release(record)
+		} else {
+			err = idempotency.Complete(record, recorder.status, recorder.Header(), recorder.body.String())
+		}
+		if err != nil {
+			log.Printf("Failed to store response for Idempotency-Key %q: %v", key, err)
+		}
+	})
+}
+
+// isRetryableStatus reports whether a response is transient, so that a retry with the
+// same key should run the request again instead of replaying it
+func isRetryableStatus(status int) bool {
+	return status == 0 || status == http.StatusConflict || status == http.StatusTooManyRequests ||
+		status >= http.StatusInternalServerError
+}
+
+// bodyLimit returns how much of the request body may be buffered: the upload limit
+// of the route for multipart uploads, and maxIdempotentBodyBytes otherwise
+func bodyLimit(r *http.Request) int64 {
+	if route := mux.CurrentRoute(r); route != nil && isMultipart(r) {
+		template, err := route.GetPathTemplate()
+		if files, ok := uploadRoutes[template]; err == nil && ok {
+			return multipartBodyLimit(files())
+		}
+	}
+	return maxIdempotentBodyBytes
+}
+
+// requestHash fingerprints the request a key was used for. Multipart bodies are hashed
+// by their fields and file contents in order, so that a retry encoded with another
+// boundary still matches.
+func requestHash(r *http.Request, body []byte) (string, error) {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	if !isMultipart(r) {
+		hash.Write(body)
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		digest := sha256.New()
+		if _, err := io.Copy(digest, part); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%q %q %x\n", part.FormName(), part.FileName(), digest.Sum(nil))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestIsRetryableStatus(t *testing.T) {
+	tests := map[int]bool{
+		0:                              true,
+		http.StatusOK:                  false,
+		http.StatusCreated:             false,
+		http.StatusBadRequest:          false,
+		http.StatusForbidden:           false,
+		http.StatusConflict:            true,
+		http.StatusUnprocessableEntity: false,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusServiceUnavailable:  true,
+	}
+	for status, want := range tests {
+		if got := isRetryableStatus(status); got != want {
+			t.Errorf("isRetryableStatus(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestRequestHash(t *testing.T) {
+	hash := func(method, target, body string) string {
+		t.Helper()
+		h, err := requestHash(httptest.NewRequest(method, target, nil), []byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	base := hash("POST", "/photos", `{"title":"a"}`)
+
+	if hash("POST", "/photos", `{"title":"a"}`) != base {
+		t.Error("the same request should hash the same")
+	}
+	others := map[string]string{
+		"body":   hash("POST", "/photos", `{"title":"b"}`),
+		"path":   hash("POST", "/comments", `{"title":"a"}`),
+		"query":  hash("POST", "/photos?draft=1", `{"title":"a"}`),
+		"method": hash("PUT", "/photos", `{"title":"a"}`),
+	}
+	for name, h := range others {
+		if h == base {
+			t.Errorf("a different %s should change the hash", name)
+		}
+	}
+}
+
+// multipartUpload encodes a form with a title and the given files in the "photo" field
+func multipartUpload(t *testing.T, boundary, title string, files ...string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.SetBoundary(boundary); err != nil {
+		t.Fatal(err)
+	}
+	form.WriteField("title", title)
+	for i, file := range files {
+		part, _ := form.CreateFormFile("photo", fmt.Sprintf("%d.jpg", i))
+		io.WriteString(part, file)
+	}
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/photos", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	return r
+}
+
+func TestRequestHashMultipart(t *testing.T) {
+	hash := func(r *http.Request) string {
+		t.Helper()
+		body, _ := io.ReadAll(r.Body)
+		h, err := requestHash(r, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	base := hash(multipartUpload(t, "first-boundary", "sunset", "image one", "image two"))
+
+	if hash(multipartUpload(t, "retry-boundary", "sunset", "image one", "image two")) != base {
+		t.Error("a retry with a new boundary should hash the same")
+	}
+	others := map[string]string{
+		"title":      hash(multipartUpload(t, "first-boundary", "sunrise", "image one", "image two")),
+		"file":       hash(multipartUpload(t, "first-boundary", "sunset", "image one", "image 2")),
+		"file order": hash(multipartUpload(t, "first-boundary", "sunset", "image two", "image one")),
+		"file count": hash(multipartUpload(t, "first-boundary", "sunset", "image one")),
+	}
+	for name, h := range others {
+		if h == base {
+			t.Errorf("a different %s should change the hash", name)
+		}
+	}
+
+	r := multipartUpload(t, "first-boundary", "sunset", "image one")
+	if _, err := requestHash(r, []byte("--first-boundary\r\nbroken")); err == nil {
+		t.Error("expected an error for a malformed multipart body")
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	t.Setenv("UPLOAD_MAX_BYTES", "1000")
+	t.Setenv("POST_MAX_MEDIA", "3")
+
+	var got int64
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = bodyLimit(r) })
+	})
+	noop := func(http.ResponseWriter, *http.Request) {}
+	router.HandleFunc("/photos", noop).Methods("POST")
+	router.HandleFunc("/stories", noop).Methods("POST")
+	router.HandleFunc("/comments", noop).Methods("POST")
+
+	story := multipartUpload(t, "b", "x")
+	story.URL.Path = "/stories"
+	tests := []struct {
+		request *http.Request
+		want    int64
+	}{
+		{multipartUpload(t, "b", "x"), 3*1000 + 1<<20},
+		{story, 1000 + 1<<20},
+		{httptest.NewRequest(http.MethodPost, "/photos", strings.NewReader("{}")), maxIdempotentBodyBytes},
+		{httptest.NewRequest(http.MethodPost, "/comments", strings.NewReader("{}")), maxIdempotentBodyBytes},
+	}
+
+	for _, tt := range tests {
+		got = 0
+		router.ServeHTTP(httptest.NewRecorder(), tt.request)
+		if got != tt.want {
+			t.Errorf("%s %s: got %d, want %d", tt.request.Header.Get("Content-Type"), tt.request.URL.Path, got, tt.want)
+		}
+	}
+}
+
+func TestResponseRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	recorder := &responseRecorder{ResponseWriter: w}
+	recorder.Header().Set("Location", "/photos/1")
+	recorder.WriteHeader(http.StatusCreated)
+	recorder.WriteHeader(http.StatusOK) // ignored, like net/http does
+	recorder.Write([]byte("hello "))
+	recorder.Write([]byte("world"))
+
+	if recorder.status != http.StatusCreated || recorder.body.String() != "hello world" {
+		t.Errorf("recorded %d %q", recorder.status, recorder.body.String())
+	}
+	if w.Code != http.StatusCreated || w.Body.String() != "hello world" || w.Header().Get("Location") != "/photos/1" {
+		t.Errorf("passed through %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	implicit := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+	implicit.Write([]byte("x"))
+	if implicit.status != http.StatusOK {
+		t.Errorf("a write without a status should record 200, got %d", implicit.status)
+	}
+}
+
+// TestIdempotencyPassThrough covers the requests the middleware leaves alone or
+// rejects before claiming a key
+func TestIdempotencyPassThrough(t *testing.T) {
+	// parseTokenUserID verifies with a fixed key rather than SECRET_KEY
+	defaultKey := secretKey
+	secretKey = []byte("your-secret-key")
+	defer func() { secretKey = defaultKey }()
+
+	token, err := generateToken(7, "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		key        string
+		auth       bool
+		body       string
+		wantStatus int
+		wantNext   bool
+	}{
+		{"GET request", http.MethodGet, "k1", true, "", http.StatusTeapot, true},
+		{"no key", http.MethodPost, "", true, "{}", http.StatusTeapot, true},
+		{"anonymous", http.MethodPost, "k1", false, "{}", http.StatusTeapot, true},
+		{"key too long", http.MethodPost, strings.Repeat("k", maxIdempotencyKeyLength+1), true, "{}", http.StatusBadRequest, false},
+		{"body too large", http.MethodPost, "k1", true, strings.Repeat("x", maxIdempotentBodyBytes+1), http.StatusRequestEntityTooLarge, false},
+	}
+	for _, tt := range tests {
+		called := false
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.WriteHeader(http.StatusTeapot)
+		})
+
+		r := httptest.NewRequest(tt.method, "/photos", strings.NewReader(tt.body))
+		if tt.key != "" {
+			r.Header.Set("Idempotency-Key", tt.key)
+		}
+		if tt.auth {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		Idempotency(next).ServeHTTP(w, r)
+
+		if w.Code != tt.wantStatus || called != tt.wantNext {
+			t.Errorf("%s: got status %d, next called %v; want %d, %v", tt.name, w.Code, called, tt.wantStatus, tt.wantNext)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/gorilla/mux"
+)
+
+// LikePhoto handles liking a photo the caller may see. Liking twice is a no-op.
+func LikePhoto(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	photoID, err := strconv.Atoi(mux.Vars(r)["photoID"])
+	if err != nil {
+		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
+		return
+	}
+
+	var photo models.Photo
+	err = database.GetDB().First(&photo, photoID).Error
+	if err != nil || !canViewPhoto(userID, photo) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+
+	like := models.Like{UserID: userID, PhotoID: photo.ID}
+	err = database.GetDB().Where(models.Like{UserID: userID, PhotoID: photo.ID}).FirstOrCreate(&like).Error
+	if err != nil {
+		http.Error(w, "Failed to like photo", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the like
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(like)
+}
+
+// UnlikePhoto handles removing the caller's like from a photo
+func UnlikePhoto(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	photoID, err := strconv.Atoi(mux.Vars(r)["photoID"])
+	if err != nil {
+		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.GetDB().Where("user_id = ? AND photo_id = ?", userID, photoID).Delete(models.Like{})
+	if result.Error != nil {
+		http.Error(w, "Failed to unlike photo", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Photo is not liked", http.StatusNotFound)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
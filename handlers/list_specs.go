@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"github.com/faazabilamri7/mygram/listing"
+)
+
+// photoListSpec describes the sort fields and filters of GET /photos
+var photoListSpec = listing.Spec{
+	IDColumn: "photos.id",
+	Sorts: []listing.Sort{
+		{Name: "created_at", Column: "photos.created_at", Field: "CreatedAt", Kind: listing.Time},
+		{Name: "updated_at", Column: "photos.updated_at", Field: "UpdatedAt", Kind: listing.Time},
+		{Name: "id", Column: "photos.id", Field: "ID", Kind: listing.Int},
+	},
+	DefaultSort: "-created_at",
+	Filters: []listing.Filter{
+		{Param: "user_id", Column: "photos.user_id", Operator: "=", Kind: listing.Int},
+		{Param: "visibility", Column: "photos.visibility", Operator: "=", Kind: listing.String},
+		{Param: "created_after", Column: "photos.created_at", Operator: ">", Kind: listing.Time},
+		{Param: "created_before", Column: "photos.created_at", Operator: "<", Kind: listing.Time},
+	},
+}
+
+// commentListSpec describes the sort fields and filters of GET /comments
+var commentListSpec = listing.Spec{
+	IDColumn: "comments.id",
+	Sorts: []listing.Sort{
+		{Name: "created_at", Column: "comments.created_at", Field: "CreatedAt", Kind: listing.Time},
+		{Name: "updated_at", Column: "comments.updated_at", Field: "UpdatedAt", Kind: listing.Time},
+		{Name: "id", Column: "comments.id", Field: "ID", Kind: listing.Int},
+	},
+	DefaultSort: "-created_at",
+	Filters: []listing.Filter{
+		{Param: "user_id", Column: "comments.user_id", Operator: "=", Kind: listing.Int},
+		{Param: "photo_id", Column: "comments.photo_id", Operator: "=", Kind: listing.Int},
+		{Param: "created_after", Column: "comments.created_at", Operator: ">", Kind: listing.Time},
+		{Param: "created_before", Column: "comments.created_at", Operator: "<", Kind: listing.Time},
+	},
+}
+
+// socialMediaListSpec describes the sort fields and filters of GET /socialmedias
+var socialMediaListSpec = listing.Spec{
+	IDColumn: "social_media.id",
+	Sorts: []listing.Sort{
+		{Name: "created_at", Column: "social_media.created_at", Field: "CreatedAt", Kind: listing.Time},
+		{Name: "name", Column: "social_media.name", Field: "Name", Kind: listing.String},
+		{Name: "id", Column: "social_media.id", Field: "ID", Kind: listing.Int},
+	},
+	DefaultSort: "-created_at",
+	Filters: []listing.Filter{
+		{Param: "name", Column: "social_media.name", Operator: "=", Kind: listing.String},
+		{Param: "created_after", Column: "social_media.created_at", Operator: ">", Kind: listing.Time},
+		{Param: "created_before", Column: "social_media.created_at", Operator: "<", Kind: listing.Time},
+	},
+}
+
+// bookmarkListSpec describes the sort fields and filters of GET /users/me/bookmarks
+var bookmarkListSpec = listing.Spec{
+	IDColumn: "bookmarks.id",
+	Sorts: []listing.Sort{
+		{Name: "created_at", Column: "bookmarks.created_at", Field: "CreatedAt", Kind: listing.Time},
+		{Name: "id", Column: "bookmarks.id", Field: "ID", Kind: listing.Int},
+	},
+	DefaultSort: "-created_at",
+	Filters: []listing.Filter{
+		{Param: "collection_id", Column: "bookmarks.collection_id", Operator: "=", Kind: listing.Int},
+	},
+}
+
+// conversationListSpec describes the sort fields of GET /conversations
+var conversationListSpec = listing.Spec{
+	IDColumn: "conversations.id",
+	Sorts: []listing.Sort{
+		{Name: "updated_at", Column: "conversations.updated_at", Field: "UpdatedAt", Kind: listing.Time},
+		{Name: "created_at", Column: "conversations.created_at", Field: "CreatedAt", Kind: listing.Time},
+	},
+	DefaultSort: "-updated_at",
+}
+
+// messageListSpec describes the sort fields and filters of GET /conversations/{conversationID}/messages
+var messageListSpec = listing.Spec{
+	IDColumn: "messages.id",
+	Sorts: []listing.Sort{
+		{Name: "created_at", Column: "messages.created_at", Field: "CreatedAt", Kind: listing.Time},
+		{Name: "id", Column: "messages.id", Field: "ID", Kind: listing.Int},
+	},
+	DefaultSort: "-created_at",
+	Filters: []listing.Filter{
+		{Param: "created_after", Column: "messages.created_at", Operator: ">", Kind: listing.Time},
+		{Param: "created_before", Column: "messages.created_at", Operator: "<", Kind: listing.Time},
+	},
+}
+
+// storyListSpec describes the sort fields and filters of GET /stories/archive
+var storyListSpec = listing.Spec{
+	IDColumn: "stories.id",
+	Sorts: []listing.Sort{
+		{Name: "created_at", Column: "stories.created_at", Field: "CreatedAt", Kind: listing.Time},
+		{Name: "id", Column: "stories.id", Field: "ID", Kind: listing.Int},
+	},
+	DefaultSort: "-created_at",
+	Filters: []listing.Filter{
+		{Param: "created_after", Column: "stories.created_at", Operator: ">", Kind: listing.Time},
+		{Param: "created_before", Column: "stories.created_at", Operator: "<", Kind: listing.Time},
+	},
+}
+
+// draftListSpec describes the sort fields and filters of GET /photos/drafts
+var draftListSpec = listing.Spec{
+	IDColumn: "photos.id",
+	Sorts: []listing.Sort{
+		{Name: "created_at", Column: "photos.created_at", Field: "CreatedAt", Kind: listing.Time},
+		{Name: "updated_at", Column: "photos.updated_at", Field: "UpdatedAt", Kind: listing.Time},
+		{Name: "id", Column: "photos.id", Field: "ID", Kind: listing.Int},
+	},
+	DefaultSort: "-updated_at",
+	Filters: []listing.Filter{
+		{Param: "status", Column: "photos.status", Operator: "=", Kind: listing.String},
+	},
+}
+
+// albumListSpec describes the sort fields and filters of GET /albums
+var albumListSpec = listing.Spec{
+	IDColumn: "albums.id",
+	Sorts: []listing.Sort{
+		{Name: "created_at", Column: "albums.created_at", Field: "CreatedAt", Kind: listing.Time},
+		{Name: "updated_at", Column: "albums.updated_at", Field: "UpdatedAt", Kind: listing.Time},
+		{Name: "title", Column: "albums.title", Field: "Title", Kind: listing.String},
+		{Name: "id", Column: "albums.id", Field: "ID", Kind: listing.Int},
+	},
+	DefaultSort: "-created_at",
+	Filters: []listing.Filter{
+		{Param: "user_id", Column: "albums.user_id", Operator: "=", Kind: listing.Int},
+		{Param: "visibility", Column: "albums.visibility", Operator: "=", Kind: listing.String},
+	},
+}
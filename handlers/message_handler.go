@@ -0,0 +1,574 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/listing"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/realtime"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+)
+
+// maxMessageLength is the longest message body accepted
+const maxMessageLength = 2000
+
+// Events pushed to connected clients on GET /messages/stream
+const (
+	eventMessage = "message"
+	eventRead    = "read"
+)
+
+// streamHeartbeat keeps idle message streams open through proxies
+const streamHeartbeat = 30 * time.Second
+
+// CreateConversation handles starting a conversation with one or more users.
+// Starting a 1:1 conversation that already exists returns the existing one.
+func CreateConversation(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		UserIDs []uint `json:"user_ids"`
+		Title   string `json:"title"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var recipients []uint
+	for _, id := range uniqueIDs(req.UserIDs) {
+		if id != userID {
+			recipients = append(recipients, id)
+		}
+	}
+	if len(recipients) == 0 {
+		http.Error(w, "At least one other user is required", http.StatusBadRequest)
+		return
+	}
+	if len(recipients)+1 > models.MaxConversationSize {
+		http.Error(w, fmt.Sprintf("A conversation can have at most %d participants", models.MaxConversationSize), http.StatusBadRequest)
+		return
+	}
+
+	for _, recipientID := range recipients {
+		var count int
+		database.GetDB().Model(&models.User{}).Where("id = ?", recipientID).Count(&count)
+		if count == 0 {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if !canMessageUser(userID, recipientID) {
+			http.Error(w, "You cannot message one or more of these users", http.StatusForbidden)
+			return
+		}
+	}
+
+	// Reuse the existing 1:1 conversation between the two users
+	if len(recipients) == 1 {
+		var existing models.Conversation
+		err = database.GetDB().Where("is_group = ?", false).
+			Where("id IN (SELECT conversation_id FROM conversation_participants WHERE user_id = ?)", userID).
+			Where("id IN (SELECT conversation_id FROM conversation_participants WHERE user_id = ?)", recipients[0]).
+			First(&existing).Error
+		if err == nil {
+			conversations := []models.Conversation{existing}
+			loadConversationDetails(conversations, userID)
+
+			// Set appropriate response status and return the existing conversation
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(conversations[0])
+			return
+		}
+	}
+
+	conversation := models.Conversation{
+		IsGroup:     len(recipients) > 1,
+		Title:       strings.TrimSpace(req.Title),
+		CreatedByID: userID,
+	}
+
+	// Save conversation and its participants in one transaction
+	tx := database.GetDB().Begin()
+	err = tx.Create(&conversation).Error
+	for _, participantID := range append([]uint{userID}, recipients...) {
+		if err != nil {
+			break
+		}
+		err = tx.Create(&models.ConversationParticipant{ConversationID: conversation.ID, UserID: participantID}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to create conversation", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to create conversation", http.StatusInternalServerError)
+		return
+	}
+
+	conversations := []models.Conversation{conversation}
+	loadConversationDetails(conversations, userID)
+
+	// Set appropriate response status and return the created conversation
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(conversations[0])
+}
+
+// GetConversations handles fetching the caller's conversations, most recently active first
+func GetConversations(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query, err := listing.Parse(r.URL.Query(), conversationListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var conversations []models.Conversation
+	err = database.GetDB().
+		Where("conversations.id IN (SELECT conversation_id FROM conversation_participants WHERE user_id = ?)", userID).
+		Scopes(query.Scope).Find(&conversations).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch conversations", http.StatusInternalServerError)
+		return
+	}
+	page := listing.Paginate(query, conversations)
+	loadConversationDetails(page.Data, userID)
+
+	// Set appropriate response status and return the fetched page of conversations
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetConversationByID handles fetching one of the caller's conversations
+func GetConversationByID(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conversation, ok := findRouteConversation(w, r, userID)
+	if !ok {
+		return
+	}
+
+	conversations := []models.Conversation{conversation}
+	loadConversationDetails(conversations, userID)
+
+	// Set appropriate response status and return the fetched conversation
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(conversations[0])
+}
+
+// GetMessages handles fetching the message history of a conversation, newest first.
+// Messages from users the caller blocked or was blocked by are left out.
+func GetMessages(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conversation, ok := findRouteConversation(w, r, userID)
+	if !ok {
+		return
+	}
+
+	query, err := listing.Parse(r.URL.Query(), messageListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var messages []models.Message
+	err = database.GetDB().Where("messages.conversation_id = ?", conversation.ID).
+		Where("messages.sender_id NOT IN ("+blockedSubquery+")", userID, userID).
+		Scopes(query.Scope).Find(&messages).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch messages", http.StatusInternalServerError)
+		return
+	}
+	page := listing.Paginate(query, messages)
+	loadMessageDetails(page.Data, conversation.Participants, userID)
+
+	// Set appropriate response status and return the fetched page of messages
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// SendMessage handles posting a message to a conversation. A photo can be shared
+// if every participant may see it.
+func SendMessage(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conversation, ok := findRouteConversation(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Body    string `json:"body"`
+		PhotoID *uint  `json:"photo_id"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	body := strings.TrimSpace(req.Body)
+	if body == "" && req.PhotoID == nil {
+		http.Error(w, "Message body or photo is required", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(body)) > maxMessageLength {
+		http.Error(w, fmt.Sprintf("Message must be at most %d characters", maxMessageLength), http.StatusBadRequest)
+		return
+	}
+
+	// 1:1 conversations end when either user blocks the other or the recipient
+	// no longer accepts messages from the sender
+	recipients := conversationRecipients(conversation, userID)
+	if !conversation.IsGroup {
+		for _, recipientID := range recipients {
+			if !canMessageUser(userID, recipientID) {
+				http.Error(w, "You cannot message this user", http.StatusForbidden)
+				return
+			}
+		}
+	}
+
+	var photo *models.Photo
+	if req.PhotoID != nil {
+		photo = &models.Photo{}
+		err = database.GetDB().First(photo, *req.PhotoID).Error
+		if err != nil || !canViewPhoto(userID, *photo) {
+			http.Error(w, "Photo not found", http.StatusNotFound)
+			return
+		}
+		for _, recipientID := range recipients {
+			if !canViewPhoto(recipientID, *photo) {
+				http.Error(w, "Photo cannot be shared with everyone in this conversation", http.StatusForbidden)
+				return
+			}
+		}
+		attachVariant(photo)
+		attachMediaItems(photo)
+	}
+
+	message := models.Message{ConversationID: conversation.ID, SenderID: userID, Body: body, PhotoID: req.PhotoID}
+	now := time.Now()
+
+	// Save the message and mark it read by its sender
+	tx := database.GetDB().Begin()
+	err = tx.Create(&message).Error
+	if err == nil {
+		err = tx.Model(&models.Conversation{}).Where("id = ?", conversation.ID).Update("updated_at", now).Error
+	}
+	if err == nil {
+		err = tx.Model(&models.ConversationParticipant{}).
+			Where("conversation_id = ? AND user_id = ?", conversation.ID, userID).
+			Updates(map[string]interface{}{"last_read_message_id": message.ID, "last_read_at": now}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to send message", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to send message", http.StatusInternalServerError)
+		return
+	}
+	message.Photo = photo
+	message.ReadBy = []uint{userID}
+
+	// Deliver to connected participants who have not blocked the sender
+	delivered := []uint{userID}
+	for _, recipientID := range recipients {
+		if !isBlockedBetween(userID, recipientID) {
+			delivered = append(delivered, recipientID)
+		}
+	}
+	realtime.Publish(realtime.Event{Type: eventMessage, Data: message}, delivered...)
+
+	// Set appropriate response status and return the sent message
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// MarkConversationRead handles updating the caller's read receipt. Without a
+// message_id everything up to the latest message is marked read.
+func MarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conversation, ok := findRouteConversation(w, r, userID)
+	if !ok {
+		return
+	}
+
+	// The request body is optional
+	var req struct {
+		MessageID uint `json:"message_id"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var message models.Message
+	if req.MessageID != 0 {
+		err = database.GetDB().Where("id = ? AND conversation_id = ?", req.MessageID, conversation.ID).First(&message).Error
+	} else {
+		err = database.GetDB().Where("conversation_id = ?", conversation.ID).Order("id DESC").First(&message).Error
+	}
+	if err != nil {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+
+	// Read receipts only move forward
+	now := time.Now()
+	err = database.GetDB().Model(&models.ConversationParticipant{}).
+		Where("conversation_id = ? AND user_id = ? AND last_read_message_id < ?", conversation.ID, userID, message.ID).
+		Updates(map[string]interface{}{"last_read_message_id": message.ID, "last_read_at": now}).Error
+	if err != nil {
+		http.Error(w, "Failed to mark conversation as read", http.StatusInternalServerError)
+		return
+	}
+
+	var participant models.ConversationParticipant
+	database.GetDB().Where("conversation_id = ? AND user_id = ?", conversation.ID, userID).First(&participant)
+
+	receipt := map[string]interface{}{
+		"conversation_id":      conversation.ID,
+		"user_id":              userID,
+		"last_read_message_id": participant.LastReadMessageID,
+		"last_read_at":         participant.LastReadAt,
+	}
+	realtime.Publish(realtime.Event{Type: eventRead, Data: receipt}, append(conversationRecipients(conversation, userID), userID)...)
+
+	// Set appropriate response status and return the read receipt
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// StreamMessages handles GET /messages/stream, pushing new messages and read
+// receipts of the caller's conversations as server-sent events
+func StreamMessages(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := realtime.Subscribe(userID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event := <-events:
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				log.Printf("Failed to encode %s event: %v", event.Type, err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// canMessageUser reports whether the sender may message the recipient. Blocks in
+// either direction prevent messaging, and private accounts only accept messages
+// from their approved followers.
+func canMessageUser(senderID, recipientID uint) bool {
+	return canViewUserContent(senderID, recipientID)
+}
+
+// findRouteConversation loads the conversation named by the conversationID route
+// variable with its participants. Conversations the user is not part of are
+// reported as missing.
+func findRouteConversation(w http.ResponseWriter, r *http.Request, userID uint) (models.Conversation, bool) {
+	var conversation models.Conversation
+	conversationID, err := strconv.Atoi(mux.Vars(r)["conversationID"])
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return conversation, false
+	}
+
+	err = database.GetDB().Preload("Participants").First(&conversation, conversationID).Error
+	if err == nil {
+		for _, participant := range conversation.Participants {
+			if participant.UserID == userID {
+				return conversation, true
+			}
+		}
+	}
+	http.Error(w, "Conversation not found", http.StatusNotFound)
+	return conversation, false
+}
+
+// conversationRecipients returns the participants of the conversation other than the user
+func conversationRecipients(conversation models.Conversation, userID uint) []uint {
+	recipients := []uint{}
+	for _, participant := range conversation.Participants {
+		if participant.UserID != userID {
+			recipients = append(recipients, participant.UserID)
+		}
+	}
+	return recipients
+}
+
+// loadConversationDetails sets the participants, last visible message and the
+// viewer's unread count on each conversation
+func loadConversationDetails(conversations []models.Conversation, viewerID uint) {
+	if len(conversations) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+	}
+
+	var participants []models.ConversationParticipant
+	database.GetDB().Where("conversation_id IN (?)", ids).Order("id").Find(&participants)
+	byConversation := map[uint][]models.ConversationParticipant{}
+	for _, participant := range participants {
+		byConversation[participant.ConversationID] = append(byConversation[participant.ConversationID], participant)
+	}
+
+	visible := func(db *gorm.DB) *gorm.DB {
+		return db.Where("sender_id NOT IN ("+blockedSubquery+")", viewerID, viewerID)
+	}
+
+	var lastMessages []models.Message
+	database.GetDB().Where("id IN (?)", database.GetDB().Model(&models.Message{}).
+		Select("MAX(id)").Where("conversation_id IN (?)", ids).Scopes(visible).Group("conversation_id").SubQuery()).
+		Find(&lastMessages)
+	lastByConversation := map[uint]models.Message{}
+	for _, message := range lastMessages {
+		lastByConversation[message.ConversationID] = message
+	}
+
+	var unread []struct {
+		ConversationID uint
+		Count          int
+	}
+	database.GetDB().Table("messages").Select("messages.conversation_id, COUNT(*) AS count").
+		Joins("JOIN conversation_participants ON conversation_participants.conversation_id = messages.conversation_id AND conversation_participants.user_id = ?", viewerID).
+		Where("messages.conversation_id IN (?) AND messages.id > conversation_participants.last_read_message_id", ids).
+		Where("messages.sender_id <> ?", viewerID).Scopes(visible).
+		Group("messages.conversation_id").Scan(&unread)
+	unreadByConversation := map[uint]int{}
+	for _, row := range unread {
+		unreadByConversation[row.ConversationID] = row.Count
+	}
+
+	for i := range conversations {
+		id := conversations[i].ID
+		conversations[i].Participants = byConversation[id]
+		conversations[i].UnreadCount = unreadByConversation[id]
+		if message, ok := lastByConversation[id]; ok {
+			messages := []models.Message{message}
+			loadMessageDetails(messages, conversations[i].Participants, viewerID)
+			conversations[i].LastMessage = &messages[0]
+		}
+	}
+}
+
+// loadMessageDetails sets the shared photos the viewer may see and who has read
+// each message
+func loadMessageDetails(messages []models.Message, participants []models.ConversationParticipant, viewerID uint) {
+	var photoIDs []uint
+	for _, message := range messages {
+		if message.PhotoID != nil {
+			photoIDs = append(photoIDs, *message.PhotoID)
+		}
+	}
+
+	photos := map[uint]models.Photo{}
+	if len(photoIDs) > 0 {
+		var rows []models.Photo
+		condition, args := visiblePhotoCondition(viewerID)
+		database.GetDB().Where("id IN (?)", photoIDs).Where(condition, args...).Find(&rows)
+		attachVariants(rows)
+		attachMedia(rows)
+		markBookmarked(viewerID, rows)
+		for _, photo := range rows {
+			photos[photo.ID] = photo
+		}
+	}
+
+	for i := range messages {
+		if messages[i].PhotoID != nil {
+			if photo, ok := photos[*messages[i].PhotoID]; ok {
+				messages[i].Photo = &photo
+			}
+		}
+		messages[i].ReadBy = []uint{}
+		for _, participant := range participants {
+			if participant.LastReadMessageID >= messages[i].ID {
+				messages[i].ReadBy = append(messages[i].ReadBy, participant.UserID)
+			}
+		}
+	}
+}
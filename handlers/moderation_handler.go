@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/search"
+	"github.com/faazabilamri7/mygram/trash"
+	"github.com/faazabilamri7/mygram/webhooks"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+)
+
+// GetModerationReports handles fetching the moderation queue. By default open and
+// in-review reports are returned, oldest first.
+func GetModerationReports(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireModerator(w, r); !ok {
+		return
+	}
+
+	query := database.GetDB().Order("created_at")
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status IN (?)", []string{models.ReportOpen, models.ReportInReview})
+	}
+	if targetType := r.URL.Query().Get("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+
+	var reports []models.Report
+	err := query.Find(&reports).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch reports", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched reports
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reports)
+}
+
+// GetModerationReportByID handles fetching a report with the reported content and its audit trail
+func GetModerationReportByID(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireModerator(w, r); !ok {
+		return
+	}
+
+	report, ok := findReport(w, r)
+	if !ok {
+		return
+	}
+
+	var actions []models.ModerationAction
+	database.GetDB().Where("target_type = ? AND target_id = ?", report.TargetType, report.TargetID).
+		Order("created_at").Find(&actions)
+
+	// Set appropriate response status and return the report details
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"report":  report,
+		"target":  loadReportTarget(report),
+		"actions": actions,
+	})
+}
+
+// UpdateModerationReport handles moving a report between triage states
+func UpdateModerationReport(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	report, ok := findReport(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Status {
+	case models.ReportOpen, models.ReportInReview, models.ReportDismissed:
+	default:
+		http.Error(w, "Invalid report status", http.StatusBadRequest)
+		return
+	}
+
+	report.Status = req.Status
+	report.ModeratorID = &moderatorID
+
+	tx := database.GetDB().Begin()
+	err = tx.Save(&report).Error
+	if err == nil {
+		err = tx.Create(&models.ModerationAction{
+			ReportID:    &report.ID,
+			ModeratorID: moderatorID,
+			TargetType:  report.TargetType,
+			TargetID:    report.TargetID,
+			Action:      models.ActionTriage,
+			Note:        fmt.Sprintf("status set to %s. %s", req.Status, req.Note),
+		}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to update report", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to update report", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the updated report
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// ApplyModerationAction handles applying hide, approve, delete, warn or suspend to the
+// reported content or its author. Approving makes hidden content visible again. The
+// report, and any other open report on the same target, is resolved.
+func ApplyModerationAction(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	report, ok := findReport(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Action      string `json:"action"`
+		Note        string `json:"note"`
+		SuspendDays int    `json:"suspend_days"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// The action, its audit record and the resolved reports are committed together
+	tx := database.GetDB().Begin()
+	afterCommit, err := applyModeration(tx, report, req.Action, req.SuspendDays)
+	if err != nil {
+		tx.Rollback()
+		if _, ok := err.(moderationError); ok {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to apply moderation action", http.StatusInternalServerError)
+		return
+	}
+
+	action := models.ModerationAction{
+		ReportID:    &report.ID,
+		ModeratorID: moderatorID,
+		TargetType:  report.TargetType,
+		TargetID:    report.TargetID,
+		Action:      req.Action,
+		Note:        req.Note,
+	}
+	err = tx.Create(&action).Error
+	if err == nil {
+		err = tx.Model(&models.Report{}).
+			Where("target_type = ? AND target_id = ? AND status IN (?)", report.TargetType, report.TargetID,
+				[]string{models.ReportOpen, models.ReportInReview}).
+			Updates(map[string]interface{}{"status": models.ReportResolved, "moderator_id": moderatorID}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to record moderation action", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to record moderation action", http.StatusInternalServerError)
+		return
+	}
+	if afterCommit != nil {
+		afterCommit()
+	}
+
+	// Set appropriate response status and return the recorded action
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(action)
+}
+
+// GetModerationActions handles fetching the audit trail of moderator decisions
+func GetModerationActions(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireModerator(w, r); !ok {
+		return
+	}
+
+	query := database.GetDB().Order("created_at desc").Limit(200)
+	if targetType := r.URL.Query().Get("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := r.URL.Query().Get("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+	if moderatorID := r.URL.Query().Get("moderator_id"); moderatorID != "" {
+		query = query.Where("moderator_id = ?", moderatorID)
+	}
+
+	var actions []models.ModerationAction
+	err := query.Find(&actions).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch moderation actions", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched actions
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(actions)
+}
+
+// moderationError is a moderation request the report target does not allow, as
+// opposed to a database failure
+type moderationError string
+
+func (e moderationError) Error() string {
+	return string(e)
+}
+
+// applyModeration performs a moderation action on the report target in the transaction.
+// The returned function, when not nil, sends the notifications and removes stored files;
+// it must only be called once the transaction is committed.
+func applyModeration(tx *gorm.DB, report models.Report, action string, suspendDays int) (func(), error) {
+	switch action {
+	case models.ActionHide:
+		switch report.TargetType {
+		case models.ReportPhoto:
+			return nil, tx.Model(&models.Photo{}).Where("id = ?", report.TargetID).UpdateColumn("hidden", true).Error
+		case models.ReportComment:
+			err := tx.Model(&models.Comment{}).Where("id = ?", report.TargetID).UpdateColumn("hidden", true).Error
+			if err != nil {
+				return nil, err
+			}
+			return func() { search.Remove(search.TypeComment, report.TargetID) }, nil
+		}
+		return nil, moderationError("Only photos and comments can be hidden")
+
+	case models.ActionApprove:
+		switch report.TargetType {
+		case models.ReportPhoto:
+			return nil, tx.Model(&models.Photo{}).Where("id = ?", report.TargetID).UpdateColumn("hidden", false).Error
+		case models.ReportComment:
+			var comment models.Comment
+			if err := tx.First(&comment, report.TargetID).Error; err != nil {
+				return nil, moderationError("Comment not found")
+			}
+			err := tx.Model(&comment).UpdateColumns(map[string]interface{}{"hidden": false, "shadow_hidden": false}).Error
+			if err != nil {
+				return nil, err
+			}
+			// Approved comments become searchable
+			comment.Hidden, comment.ShadowHidden = false, false
+			return func() { indexComment(comment) }, nil
+		}
+		return nil, moderationError("Only photos and comments can be approved")
+
+	case models.ActionDelete:
+		switch report.TargetType {
+		case models.ReportPhoto:
+			var photo models.Photo
+			if err := tx.First(&photo, report.TargetID).Error; err != nil {
+				return nil, moderationError("Photo not found")
+			}
+			removeFiles, err := trash.PurgePhotoTx(tx, photo)
+			if err != nil {
+				return nil, err
+			}
+			return func() {
+				removeFiles()
+				webhooks.Dispatch(webhooks.PhotoDeleted, photo, photo.UserID)
+				search.Remove(search.TypePhoto, photo.ID)
+			}, nil
+		case models.ReportComment:
+			var comment models.Comment
+			if err := tx.First(&comment, report.TargetID).Error; err != nil {
+				return nil, moderationError("Comment not found")
+			}
+			audience := commentAudience(comment)
+			if err := trash.PurgeCommentTx(tx, comment); err != nil {
+				return nil, err
+			}
+			return func() {
+				webhooks.Dispatch(webhooks.CommentDeleted, comment, audience...)
+				search.Remove(search.TypeComment, comment.ID)
+			}, nil
+		}
+		return nil, moderationError("Only photos and comments can be deleted")
+
+	case models.ActionWarn:
+		userID, err := reportTargetOwner(report)
+		if err != nil {
+			return nil, err
+		}
+		return nil, tx.Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumn("warning_count", gorm.Expr("warning_count + ?", 1)).Error
+
+	case models.ActionSuspend:
+		if suspendDays <= 0 {
+			return nil, moderationError("suspend_days must be positive")
+		}
+		userID, err := reportTargetOwner(report)
+		if err != nil {
+			return nil, err
+		}
+		until := time.Now().AddDate(0, 0, suspendDays)
+		return nil, tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("suspended_until", until).Error
+	}
+
+	return nil, moderationError("Invalid moderation action")
+}
+
+// reportTargetOwner returns the user responsible for the reported content
+func reportTargetOwner(report models.Report) (uint, error) {
+	switch report.TargetType {
+	case models.ReportUser:
+		return report.TargetID, nil
+	case models.ReportPhoto:
+		var photo models.Photo
+		if err := database.GetDB().First(&photo, report.TargetID).Error; err != nil {
+			return 0, moderationError("Photo not found")
+		}
+		return photo.UserID, nil
+	case models.ReportComment:
+		var comment models.Comment
+		if err := database.GetDB().First(&comment, report.TargetID).Error; err != nil {
+			return 0, moderationError("Comment not found")
+		}
+		return comment.UserID, nil
+	}
+	return 0, moderationError("Invalid report target")
+}
+
+// loadReportTarget returns the reported photo, comment or user, or nil if it no longer exists
+func loadReportTarget(report models.Report) interface{} {
+	switch report.TargetType {
+	case models.ReportPhoto:
+		var photo models.Photo
+		if database.GetDB().First(&photo, report.TargetID).Error == nil {
+			return photo
+		}
+	case models.ReportComment:
+		var comment models.Comment
+		if database.GetDB().First(&comment, report.TargetID).Error == nil {
+			return comment
+		}
+	case models.ReportUser:
+		var user models.User
+		if database.GetDB().First(&user, report.TargetID).Error == nil {
+			user.Password = ""
+			return user
+		}
+	}
+	return nil
+}
+
+// findReport loads the report from the route
+func findReport(w http.ResponseWriter, r *http.Request) (models.Report, bool) {
+	var report models.Report
+
+	reportID, err := strconv.Atoi(mux.Vars(r)["reportID"])
+	if err != nil {
+		http.Error(w, "Invalid report ID", http.StatusBadRequest)
+		return report, false
+	}
+
+	err = database.GetDB().First(&report, reportID).Error
+	if err != nil {
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return report, false
+	}
+
+	return report, true
+}
+
+// requireModerator checks that the caller is a moderator and returns their user ID
+func requireModerator(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return 0, false
+	}
+
+	var user models.User
+	err = database.GetDB().Select("id, role").First(&user, userID).Error
+	if err != nil || user.Role != models.RoleModerator {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return 0, false
+	}
+
+	return userID, true
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/faazabilamri7/mygram/models"
+)
+
+func TestApplyModerationRejectsInvalidRequests(t *testing.T) {
+	userReport := models.Report{TargetType: models.ReportUser, TargetID: 3}
+	tests := []struct {
+		action      string
+		suspendDays int
+	}{
+		{"shrug", 0},
+		{models.ActionHide, 0},
+		{models.ActionApprove, 0},
+		{models.ActionDelete, 0},
+		{models.ActionSuspend, 0},
+		{models.ActionSuspend, -2},
+	}
+	for _, tt := range tests {
+		// None of these reach the database, so no transaction is needed
+		afterCommit, err := applyModeration(nil, userReport, tt.action, tt.suspendDays)
+		if _, ok := err.(moderationError); !ok || afterCommit != nil {
+			t.Errorf("%s (%d days): got %v, want a moderationError", tt.action, tt.suspendDays, err)
+		}
+	}
+}
@@ -2,23 +2,26 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/imaging"
+	"github.com/faazabilamri7/mygram/listing"
 	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/publishing"
+	"github.com/faazabilamri7/mygram/search"
+	"github.com/faazabilamri7/mygram/webhooks"
 	"github.com/gorilla/mux"
 )
 
-// CreatePhoto handles the creation of a new photo
+// CreatePhoto handles the creation of a new photo post. It accepts a JSON body with a
+// photo_url or a "media" list of image URLs, or a multipart upload with up to the
+// configured number of images in the "media" field (or a single one in "photo")
+// and their alt texts in matching "alt_text" fields. All items are created at once.
 func CreatePhoto(w http.ResponseWriter, r *http.Request) {
-	var photo models.Photo
-	err := json.NewDecoder(r.Body).Decode(&photo)
-	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
 	// Retrieve user ID from token or request context
 	userID, err := getUserIDFromToken(r)
 	if err != nil {
@@ -26,39 +29,232 @@ func CreatePhoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var photo models.Photo
+	var uploads []uploadedImage
+	if isMultipart(r) {
+		uploads, err = readPostImages(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), uploadErrorStatus(err))
+			return
+		}
+		photo.Title = r.FormValue("title")
+		photo.Caption = r.FormValue("caption")
+		photo.Visibility = r.FormValue("visibility")
+		photo.Status = r.FormValue("status")
+		photo.CommentPolicy = r.FormValue("comment_policy")
+		if raw := r.FormValue("publish_at"); raw != "" {
+			publishAt, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				http.Error(w, "Invalid publish_at time", http.StatusBadRequest)
+				return
+			}
+			photo.PublishAt = &publishAt
+		}
+
+		// Apply the EXIF orientation and strip all metadata before storing
+		var meta imaging.Metadata
+		for i := range uploads {
+			var itemMeta imaging.Metadata
+			uploads[i].Data, uploads[i].ContentType, itemMeta, err = imaging.Sanitize(uploads[i].Data, uploads[i].ContentType)
+			if err != nil {
+				writeImageError(w, err)
+				return
+			}
+			if i == 0 {
+				meta = itemMeta
+			}
+		}
+
+		// Keep the whitelisted metadata of the cover only for users who opted in
+		var owner models.User
+		err = database.GetDB().Select("id, keep_photo_metadata").First(&owner, userID).Error
+		if err == nil && owner.KeepPhotoMetadata {
+			photo.CameraMake = meta.CameraMake
+			photo.CameraModel = meta.CameraModel
+			photo.TakenAt = meta.TakenAt
+		}
+
+		altTexts := r.MultipartForm.Value["alt_text"]
+		photo.Media = make([]models.PhotoMedia, len(uploads))
+		for i := range photo.Media {
+			if i < len(altTexts) {
+				photo.Media[i].AltText = altTexts[i]
+			}
+		}
+	} else {
+		err = json.NewDecoder(r.Body).Decode(&photo)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		photo.ID = 0
+		photo.CreatedAt = time.Time{}
+		photo.UpdatedAt = time.Time{}
+		photo.DeletedAt = nil
+		photo.Comments = nil
+		photo.StorageKey = ""
+		photo.VariantsStatus = ""
+		photo.Edited = false
+		photo.EditedAt = nil
+		photo.PinnedComments = nil
+		photo.CameraMake = ""
+		photo.CameraModel = ""
+		photo.TakenAt = nil
+
+		// A single image post only needs its photo_url
+		if len(photo.Media) == 0 {
+			photo.Media = []models.PhotoMedia{{URL: photo.URL}}
+		} else {
+			for i := range photo.Media {
+				if photo.Media[i].URL == "" {
+					http.Error(w, "Media URL is required", http.StatusBadRequest)
+					return
+				}
+				photo.Media[i].ID = 0
+				photo.Media[i].StorageKey = ""
+				photo.Media[i].CreatedAt = time.Time{}
+				photo.Media[i].UpdatedAt = time.Time{}
+			}
+		}
+	}
+
+	if len(photo.Media) > maxMediaItems() {
+		http.Error(w, mediaLimitError(), http.StatusBadRequest)
+		return
+	}
+
 	// Set user ID for the photo
 	photo.UserID = userID
 
-	// Save photo to the database
-	err = database.GetDB().Create(&photo).Error
+	visibility, ok := normalizePhotoVisibility(photo.Visibility)
+	if !ok {
+		http.Error(w, "Invalid photo visibility", http.StatusBadRequest)
+		return
+	}
+	photo.Visibility = visibility
+
+	commentPolicy, ok := normalizeCommentPolicy(photo.CommentPolicy)
+	if !ok {
+		http.Error(w, "Invalid comment policy", http.StatusBadRequest)
+		return
+	}
+	photo.CommentPolicy = commentPolicy
+
+	// Drafts and scheduled photos stay private to their owner until published
+	status, publishAt, err := normalizePhotoStatus(photo.Status, photo.PublishAt)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	photo.Status = status
+	photo.PublishAt = publishAt
+
+	// Blocked users cannot be mentioned
+	if mentionsBlockedUser(userID, photo.Caption) {
+		http.Error(w, "You cannot mention one or more of these users", http.StatusForbidden)
+		return
+	}
+
+	// Photos held by the content filter stay hidden until a moderator approves them
+	held, ok := filterContent(w, &photo.Title, &photo.Caption)
+	if !ok {
+		return
+	}
+	photo.Hidden = len(held) > 0
+
+	// Store the uploaded images and record where they are served from
+	for i, upload := range uploads {
+		err = storeMediaUpload(userID, upload, &photo.Media[i])
+		if err != nil {
+			deleteStoredMedia(photo.Media[:i])
+			http.Error(w, "Failed to store photo", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The first item is the cover of the post
+	for i := range photo.Media {
+		photo.Media[i].Position = i
+	}
+	photo.URL = photo.Media[0].URL
+	photo.StorageKey = photo.Media[0].StorageKey
+	if hasStoredMedia(photo.Media) {
+		photo.VariantsStatus = imaging.VariantsPending
+	}
+
+	// Save photo and its media items in one transaction
+	tx := database.GetDB().Begin()
+	err = tx.Create(&photo).Error
+	for i := range photo.Media {
+		if err != nil {
+			break
+		}
+		photo.Media[i].PhotoID = photo.ID
+		err = tx.Create(&photo.Media[i]).Error
+	}
+	if err == nil && len(held) > 0 {
+		err = holdForReview(tx, models.ReportPhoto, photo.ID, held)
+	}
+	if err != nil {
+		tx.Rollback()
+		deleteStoredMedia(photo.Media)
+		http.Error(w, "Failed to create photo", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		deleteStoredMedia(photo.Media)
 		http.Error(w, "Failed to create photo", http.StatusInternalServerError)
 		return
 	}
 
+	// Notify webhook subscribers and update the search index once the photo is published
+	if isPublished(photo) {
+		webhooks.Dispatch(webhooks.PhotoCreated, photo, userID)
+		search.IndexPhoto(photo)
+	}
+	if err := syncPhotoTags(photo); err != nil {
+		log.Printf("Failed to store hashtags of photo %d: %v", photo.ID, err)
+	}
+
 	// Set appropriate response status and return the created photo
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(photo)
 }
 
-// GetAllPhotos handles fetching all photos from all users
+// GetAllPhotos handles fetching all published photos from all users that the caller may see
 func GetAllPhotos(w http.ResponseWriter, r *http.Request) {
+	query, err := listing.Parse(r.URL.Query(), photoListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewerID := getOptionalUserID(r)
+	condition, args := visiblePhotoCondition(viewerID)
+	notMuted, mutedArgs := notMutedCondition("photos.user_id", viewerID)
+
 	var photos []models.Photo
-	err := database.GetDB().Preload("User").Find(&photos).Error
+	err = database.GetDB().Preload("User").Where(condition, args...).Where(notMuted, mutedArgs...).
+		Where("photos.status IN (?)", publishedStatuses).
+		Scopes(query.Scope).Find(&photos).Error
 	if err != nil {
 		http.Error(w, "Failed to fetch photos", http.StatusInternalServerError)
 		return
 	}
+	page := listing.Paginate(query, photos)
+	attachVariants(page.Data)
+	attachMedia(page.Data)
+	markBookmarked(viewerID, page.Data)
 
-	// Set appropriate response status and return the fetched photos
+	// Set appropriate response status and return the fetched page of photos
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(photos)
+	json.NewEncoder(w).Encode(page)
 }
 
 // GetPhotoByID handles fetching a photo by its ID
 func GetPhotoByID(w http.ResponseWriter, r *http.Request) {
-	photoIDStr := mux.Vars(r)["photoId"]
+	photoIDStr := mux.Vars(r)["photoID"]
 	photoID, err := strconv.Atoi(photoIDStr)
 	if err != nil {
 		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
@@ -72,6 +268,17 @@ func GetPhotoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Hidden photos are reported as missing
+	viewerID := getOptionalUserID(r)
+	if !canViewPhoto(viewerID, photo) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+	attachVariant(&photo)
+	attachMediaItems(&photo)
+	markBookmarkedPhoto(viewerID, &photo)
+	attachPinnedComments(viewerID, &photo)
+
 	// Set appropriate response status and return the fetched photo
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -87,7 +294,7 @@ func UpdatePhotoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	photoIDStr := mux.Vars(r)["photoId"]
+	photoIDStr := mux.Vars(r)["photoID"]
 	photoID, err := strconv.Atoi(photoIDStr)
 	if err != nil {
 		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
@@ -115,17 +322,110 @@ func UpdatePhotoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// An omitted visibility keeps the current one
+	if updatedPhoto.Visibility == "" {
+		updatedPhoto.Visibility = existingPhoto.Visibility
+	}
+	visibility, ok := normalizePhotoVisibility(updatedPhoto.Visibility)
+	if !ok {
+		http.Error(w, "Invalid photo visibility", http.StatusBadRequest)
+		return
+	}
+
+	// Drafts can be rescheduled or published; published photos stay published
+	wasPublished := isPublished(existingPhoto)
+	if wasPublished {
+		if updatedPhoto.Status != "" && updatedPhoto.Status != models.PhotoPublished {
+			http.Error(w, "Published photos cannot be moved back to drafts", http.StatusBadRequest)
+			return
+		}
+	} else if updatedPhoto.Status != "" || updatedPhoto.PublishAt != nil {
+		status, publishAt, err := normalizePhotoStatus(updatedPhoto.Status, updatedPhoto.PublishAt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		existingPhoto.Status = status
+		existingPhoto.PublishAt = publishAt
+	}
+
+	// Blocked users cannot be mentioned
+	if mentionsBlockedUser(userID, updatedPhoto.Caption) {
+		http.Error(w, "You cannot mention one or more of these users", http.StatusForbidden)
+		return
+	}
+
+	// A held edit hides the photo until a moderator approves it
+	held, ok := filterContent(w, &updatedPhoto.Title, &updatedPhoto.Caption)
+	if !ok {
+		return
+	}
+	if len(held) > 0 {
+		existingPhoto.Hidden = true
+	}
+
+	// Keep the previous title and caption of published photos as a revision
+	var revision *models.PhotoRevision
+	if wasPublished && (existingPhoto.Title != updatedPhoto.Title || existingPhoto.Caption != updatedPhoto.Caption) {
+		revision = &models.PhotoRevision{PhotoID: existingPhoto.ID, Title: existingPhoto.Title, Caption: existingPhoto.Caption}
+		editedAt := time.Now()
+		existingPhoto.Edited = true
+		existingPhoto.EditedAt = &editedAt
+	}
+
 	// Update photo fields
+	existingPhoto.Visibility = visibility
 	existingPhoto.Title = updatedPhoto.Title
 	existingPhoto.Caption = updatedPhoto.Caption
-	existingPhoto.URL = updatedPhoto.URL
 
-	// Save updated photo to the database
-	err = database.GetDB().Save(&existingPhoto).Error
+	// Uploaded photos keep the URL of their stored object
+	if existingPhoto.StorageKey == "" {
+		existingPhoto.URL = updatedPhoto.URL
+	}
+
+	// A draft moved to published goes through the regular publishing
+	publishNow := !wasPublished && isPublished(existingPhoto)
+	if publishNow {
+		existingPhoto.Status = models.PhotoDraft
+	}
+
+	// Save updated photo and its revision to the database
+	tx := database.GetDB().Begin()
+	err = tx.Save(&existingPhoto).Error
+	if err == nil && revision != nil {
+		err = tx.Create(revision).Error
+	}
+	if err == nil && len(held) > 0 {
+		err = holdForReview(tx, models.ReportPhoto, existingPhoto.ID, held)
+	}
 	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to update photo", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		http.Error(w, "Failed to update photo", http.StatusInternalServerError)
 		return
 	}
+	if publishNow {
+		existingPhoto, err = publishing.Publish(existingPhoto)
+		if err != nil && err != publishing.ErrAlreadyPublished {
+			http.Error(w, "Failed to publish photo", http.StatusInternalServerError)
+			return
+		}
+	}
+	attachVariant(&existingPhoto)
+	attachMediaItems(&existingPhoto)
+	markBookmarkedPhoto(userID, &existingPhoto)
+
+	// Notify webhook subscribers and update the search index of published photos
+	if wasPublished {
+		webhooks.Dispatch(webhooks.PhotoUpdated, existingPhoto, userID)
+		search.IndexPhoto(existingPhoto)
+	}
+	if err := syncPhotoTags(existingPhoto); err != nil {
+		log.Printf("Failed to store hashtags of photo %d: %v", existingPhoto.ID, err)
+	}
 
 	// Set appropriate response status and return the updated photo
 	w.WriteHeader(http.StatusOK)
@@ -141,7 +441,7 @@ func DeletePhotoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	photoIDStr := mux.Vars(r)["photoId"]
+	photoIDStr := mux.Vars(r)["photoID"]
 	photoID, err := strconv.Atoi(photoIDStr)
 	if err != nil {
 		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
@@ -162,13 +462,81 @@ func DeletePhotoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete the photo from the database
+	// Move the photo to the trash; it is purged after the retention window
 	err = database.GetDB().Delete(&existingPhoto).Error
 	if err != nil {
 		http.Error(w, "Failed to delete photo", http.StatusInternalServerError)
 		return
 	}
 
+	// Notify webhook subscribers and update the search index
+	if isPublished(existingPhoto) {
+		webhooks.Dispatch(webhooks.PhotoDeleted, existingPhoto, userID)
+		search.Remove(search.TypePhoto, existingPhoto.ID)
+	}
+
 	// Set appropriate response status
 	w.WriteHeader(http.StatusOK)
 }
+
+// attachVariants loads the resized variants of the photos with a single query
+// and sets the variants of each cover image keyed by variant name
+func attachVariants(photos []models.Photo) {
+	if len(photos) == 0 {
+		return
+	}
+
+	photoIDs := make([]uint, len(photos))
+	for i, photo := range photos {
+		photoIDs[i] = photo.ID
+	}
+
+	byPhoto, err := loadVariants(photoIDs)
+	if err != nil {
+		log.Printf("Failed to load photo variants: %v", err)
+		return
+	}
+	for i := range photos {
+		// Variants recorded before media items had their own have no source key
+		photos[i].Variants = variantsOf(byPhoto[photos[i].ID], photos[i].StorageKey, "")
+	}
+}
+
+// loadVariants loads the resized variants of the photos grouped by photo ID
+func loadVariants(photoIDs []uint) (map[uint][]models.PhotoVariant, error) {
+	var variants []models.PhotoVariant
+	err := database.GetDB().Where("photo_id IN (?)", photoIDs).Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byPhoto := make(map[uint][]models.PhotoVariant)
+	for _, variant := range variants {
+		byPhoto[variant.PhotoID] = append(byPhoto[variant.PhotoID], variant)
+	}
+	return byPhoto, nil
+}
+
+// variantsOf keys the variants rendered from one of the given source images by name
+func variantsOf(variants []models.PhotoVariant, sourceKeys ...string) map[string]models.PhotoVariant {
+	var byName map[string]models.PhotoVariant
+	for _, variant := range variants {
+		for _, key := range sourceKeys {
+			if variant.SourceKey != key {
+				continue
+			}
+			if byName == nil {
+				byName = make(map[string]models.PhotoVariant)
+			}
+			byName[variant.Name] = variant
+		}
+	}
+	return byName
+}
+
+// attachVariant loads the resized variants of a single photo
+func attachVariant(photo *models.Photo) {
+	photos := []models.Photo{*photo}
+	attachVariants(photos)
+	photo.Variants = photos[0].Variants
+}
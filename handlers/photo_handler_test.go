@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/faazabilamri7/mygram/models"
+)
+
+func TestVariantsOf(t *testing.T) {
+	variants := []models.PhotoVariant{
+		{ID: 1, Name: "thumb", SourceKey: "photos/1/a.jpg"},
+		{ID: 2, Name: "medium", SourceKey: "photos/1/a.jpg"},
+		{ID: 3, Name: "thumb", SourceKey: "photos/1/b.jpg"},
+		{ID: 4, Name: "large"},
+	}
+
+	cover := variantsOf(variants, "photos/1/a.jpg", "")
+	if len(cover) != 3 || cover["thumb"].ID != 1 || cover["medium"].ID != 2 || cover["large"].ID != 4 {
+		t.Errorf("cover variants = %+v", cover)
+	}
+
+	item := variantsOf(variants, "photos/1/b.jpg")
+	if len(item) != 1 || item["thumb"].ID != 3 {
+		t.Errorf("item variants = %+v", item)
+	}
+
+	if got := variantsOf(variants, "photos/1/c.jpg"); got != nil {
+		t.Errorf("variants of an image without variants = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/imaging"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/storage"
+	"github.com/faazabilamri7/mygram/webhooks"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+)
+
+const defaultMaxMediaItems = 10
+
+// maxMediaItems returns how many media items a post may hold, from POST_MAX_MEDIA
+func maxMediaItems() int {
+	if n, err := strconv.Atoi(os.Getenv("POST_MAX_MEDIA")); err == nil && n > 0 {
+		return n
+	}
+	return defaultMaxMediaItems
+}
+
+// ReorderPhotoMedia handles changing the order of a post's media items. The body
+// lists every media ID of the post in the new order; the first becomes the cover.
+func ReorderPhotoMedia(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	photo, ok := findOwnedRoutePhoto(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		MediaIDs []uint `json:"media_ids"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	media, err := ensurePhotoMedia(photo)
+	if err != nil {
+		http.Error(w, "Failed to reorder media", http.StatusInternalServerError)
+		return
+	}
+
+	// The new order must name every item exactly once
+	mediaIDs := uniqueIDs(req.MediaIDs)
+	if len(mediaIDs) != len(req.MediaIDs) || len(mediaIDs) != len(media) {
+		http.Error(w, "Media IDs must list every item of the post once", http.StatusBadRequest)
+		return
+	}
+	byID := map[uint]models.PhotoMedia{}
+	for _, item := range media {
+		byID[item.ID] = item
+	}
+	ordered := make([]models.PhotoMedia, len(mediaIDs))
+	for i, mediaID := range mediaIDs {
+		item, ok := byID[mediaID]
+		if !ok {
+			http.Error(w, "Media IDs must list every item of the post once", http.StatusBadRequest)
+			return
+		}
+		item.Position = i
+		ordered[i] = item
+	}
+
+	tx := database.GetDB().Begin()
+	for i := range ordered {
+		if err != nil {
+			break
+		}
+		err = tx.Model(&ordered[i]).UpdateColumn("position", ordered[i].Position).Error
+	}
+	if err == nil {
+		err = syncPhotoCover(tx, &photo, ordered, false)
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to reorder media", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to reorder media", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithUpdatedPhoto(w, photo, userID)
+}
+
+// ReplacePhotoMedia handles replacing one media item of a post. A multipart upload
+// with the image in the "media" field or a JSON body with a url replaces the image;
+// alt_text, width and height may be updated alone.
+func ReplacePhotoMedia(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	photo, ok := findOwnedRoutePhoto(w, r, userID)
+	if !ok {
+		return
+	}
+
+	mediaID, err := strconv.Atoi(mux.Vars(r)["mediaID"])
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	media, err := ensurePhotoMedia(photo)
+	if err != nil {
+		http.Error(w, "Failed to update media", http.StatusInternalServerError)
+		return
+	}
+	index := -1
+	for i, item := range media {
+		if item.ID == uint(mediaID) {
+			index = i
+		}
+	}
+	if index < 0 {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	item := media[index]
+	previousKey := item.StorageKey
+	if isMultipart(r) {
+		upload, contentType, err := readUploadedImage(w, r, "media")
+		if err != nil {
+			http.Error(w, err.Error(), uploadErrorStatus(err))
+			return
+		}
+
+		// Apply the EXIF orientation and strip all metadata before storing
+		upload, contentType, _, err = imaging.Sanitize(upload, contentType)
+		if err != nil {
+			writeImageError(w, err)
+			return
+		}
+		err = storeMediaUpload(userID, uploadedImage{Data: upload, ContentType: contentType}, &item)
+		if err != nil {
+			http.Error(w, "Failed to store photo", http.StatusInternalServerError)
+			return
+		}
+		if _, ok := r.MultipartForm.Value["alt_text"]; ok {
+			item.AltText = r.FormValue("alt_text")
+		}
+	} else {
+		var req struct {
+			URL     string  `json:"url"`
+			AltText *string `json:"alt_text"`
+			Width   *int    `json:"width"`
+			Height  *int    `json:"height"`
+		}
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.URL != "" {
+			item.URL = req.URL
+			item.StorageKey = ""
+			item.Width, item.Height = 0, 0
+		}
+		if req.AltText != nil {
+			item.AltText = *req.AltText
+		}
+		if req.Width != nil && req.Height != nil {
+			item.Width, item.Height = *req.Width, *req.Height
+		}
+	}
+	media[index] = item
+
+	tx := database.GetDB().Begin()
+	err = tx.Save(&item).Error
+	if err == nil {
+		err = syncPhotoCover(tx, &photo, media, item.StorageKey != previousKey)
+	}
+	if err != nil {
+		tx.Rollback()
+		if item.StorageKey != previousKey {
+			deleteStoredMedia([]models.PhotoMedia{item})
+		}
+		http.Error(w, "Failed to update media", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to update media", http.StatusInternalServerError)
+		return
+	}
+
+	// Remove the replaced image from storage
+	if previousKey != "" && previousKey != item.StorageKey {
+		if err := storage.GetStorage().Delete(previousKey); err != nil {
+			log.Printf("Failed to delete stored media %s: %v", previousKey, err)
+		}
+	}
+
+	respondWithUpdatedPhoto(w, photo, userID)
+}
+
+// readPostImages reads the images of a multipart post, sent as one or more "media"
+// files or as a single "photo" file
+func readPostImages(w http.ResponseWriter, r *http.Request) ([]uploadedImage, error) {
+	images, err := readUploadedImages(w, r, "media", maxMediaItems())
+	if err == errMissingUpload {
+		return readUploadedImages(w, r, "photo", 1)
+	}
+	return images, err
+}
+
+// storeMediaUpload stores an uploaded image and records its location and dimensions on item
+func storeMediaUpload(userID uint, upload uploadedImage, item *models.PhotoMedia) error {
+	key, err := newObjectKey("photos", userID, upload.ContentType)
+	if err != nil {
+		return err
+	}
+	err = storage.GetStorage().Put(key, upload.Data, upload.ContentType)
+	if err != nil {
+		log.Printf("Failed to store photo %s: %v", key, err)
+		return err
+	}
+
+	item.StorageKey = key
+	item.URL = storage.GetStorage().URL(key)
+	item.Width, item.Height, err = imaging.Dimensions(upload.Data)
+	if err != nil {
+		item.Width, item.Height = 0, 0
+	}
+	return nil
+}
+
+// hasStoredMedia reports whether any of the media items is held in storage
+func hasStoredMedia(media []models.PhotoMedia) bool {
+	for _, item := range media {
+		if item.StorageKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteStoredMedia removes the stored images of media items
+func deleteStoredMedia(media []models.PhotoMedia) {
+	for _, item := range media {
+		if item.StorageKey == "" {
+			continue
+		}
+		if err := storage.GetStorage().Delete(item.StorageKey); err != nil {
+			log.Printf("Failed to delete stored media %s: %v", item.StorageKey, err)
+		}
+	}
+}
+
+// findOwnedRoutePhoto loads the photo referenced by the photoID route variable and
+// checks that the user owns it
+func findOwnedRoutePhoto(w http.ResponseWriter, r *http.Request, userID uint) (models.Photo, bool) {
+	var photo models.Photo
+
+	photoID, err := strconv.Atoi(mux.Vars(r)["photoID"])
+	if err != nil {
+		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
+		return photo, false
+	}
+
+	err = database.GetDB().First(&photo, photoID).Error
+	if err != nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return photo, false
+	}
+	if photo.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return photo, false
+	}
+
+	return photo, true
+}
+
+// ensurePhotoMedia returns the media items of a photo in order. Photos created
+// before posts could hold several images get an item for their single image.
+func ensurePhotoMedia(photo models.Photo) ([]models.PhotoMedia, error) {
+	var media []models.PhotoMedia
+	err := database.GetDB().Where("photo_id = ?", photo.ID).Order("position, id").Find(&media).Error
+	if err != nil || len(media) > 0 {
+		return media, err
+	}
+
+	item := models.PhotoMedia{PhotoID: photo.ID, URL: photo.URL, StorageKey: photo.StorageKey}
+	err = database.GetDB().Create(&item).Error
+	return []models.PhotoMedia{item}, err
+}
+
+// syncPhotoCover mirrors the first media item to the photo's URL and regenerates
+// the variants when the cover or another stored image changed
+func syncPhotoCover(tx *gorm.DB, photo *models.Photo, media []models.PhotoMedia, imagesChanged bool) error {
+	cover := media[0]
+	coverChanged := cover.StorageKey != photo.StorageKey || cover.URL != photo.URL
+
+	photo.URL = cover.URL
+	photo.StorageKey = cover.StorageKey
+	if coverChanged || imagesChanged {
+		photo.VariantsStatus = ""
+		if hasStoredMedia(media) {
+			photo.VariantsStatus = imaging.VariantsPending
+		}
+		if err := imaging.DeleteVariants(photo.ID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(photo).UpdateColumns(map[string]interface{}{
+		"url":             photo.URL,
+		"storage_key":     photo.StorageKey,
+		"variants_status": photo.VariantsStatus,
+	}).Error
+}
+
+// respondWithUpdatedPhoto notifies webhook subscribers and returns the photo after
+// a change to its media items
+func respondWithUpdatedPhoto(w http.ResponseWriter, photo models.Photo, userID uint) {
+	attachVariant(&photo)
+	attachMediaItems(&photo)
+	markBookmarkedPhoto(userID, &photo)
+
+	// Notify webhook subscribers of changes to published photos
+	if isPublished(photo) {
+		webhooks.Dispatch(webhooks.PhotoUpdated, photo, userID)
+	}
+
+	// Set appropriate response status and return the updated photo
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(photo)
+}
+
+// attachMedia loads the media items of the photos with a single query. Photos
+// without stored items are returned with their single image as the only item.
+func attachMedia(photos []models.Photo) {
+	if len(photos) == 0 {
+		return
+	}
+
+	photoIDs := make([]uint, len(photos))
+	for i, photo := range photos {
+		photoIDs[i] = photo.ID
+	}
+
+	var media []models.PhotoMedia
+	err := database.GetDB().Where("photo_id IN (?)", photoIDs).Order("position, id").Find(&media).Error
+	if err != nil {
+		log.Printf("Failed to load photo media: %v", err)
+		return
+	}
+
+	variants, err := loadVariants(photoIDs)
+	if err != nil {
+		log.Printf("Failed to load photo variants: %v", err)
+	}
+
+	byPhoto := make(map[uint][]models.PhotoMedia)
+	for _, item := range media {
+		if item.StorageKey != "" {
+			item.Variants = variantsOf(variants[item.PhotoID], item.StorageKey)
+		}
+		byPhoto[item.PhotoID] = append(byPhoto[item.PhotoID], item)
+	}
+	for i := range photos {
+		photos[i].Media = byPhoto[photos[i].ID]
+		if len(photos[i].Media) == 0 {
+			photos[i].Media = []models.PhotoMedia{{PhotoID: photos[i].ID, URL: photos[i].URL}}
+		}
+	}
+}
+
+// attachMediaItems loads the media items of a single photo
+func attachMediaItems(photo *models.Photo) {
+	photos := []models.Photo{*photo}
+	attachMedia(photos)
+	photo.Media = photos[0].Media
+}
+
+// mediaLimitError describes the media item limit of a post
+func mediaLimitError() string {
+	return fmt.Sprintf("A post can have at most %d media items", maxMediaItems())
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faazabilamri7/mygram/ratelimit"
+)
+
+// RateLimit is a middleware limiting requests per route group with a token bucket per
+// user, or per client IP for requests without a valid token
+func RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := ratelimit.GetLimiter()
+		if limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		group := rateLimitGroup(r)
+		result, limited, err := limiter.Allow(group, rateLimitKey(r, group))
+		if err != nil {
+			// An unavailable store must not take the API down with it
+			log.Printf("Rate limiter unavailable: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !limited {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		policy := limiter.Policies[group]
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitGroup returns the route group whose policy applies to the request
+func rateLimitGroup(r *http.Request) string {
+	if r.Method == http.MethodPost && (r.URL.Path == "/users/login" || r.URL.Path == "/users/register") {
+		return ratelimit.GroupLogin
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ratelimit.GroupReads
+	}
+	return ratelimit.GroupWrites
+}
+
+// rateLimitKey identifies who the request counts against: the user of a valid token,
+// or the client IP. Login attempts always count against the client IP.
+func rateLimitKey(r *http.Request, group string) string {
+	if group != ratelimit.GroupLogin && r.Header.Get("Authorization") != "" {
+		if userID, err := parseTokenUserID(r); err == nil {
+			return fmt.Sprintf("user:%d", userID)
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP returns the address of the client. X-Forwarded-For is only trusted when
+// RATE_LIMIT_TRUST_PROXY is set, since clients can send any value.
+func clientIP(r *http.Request) string {
+	if os.Getenv("RATE_LIMIT_TRUST_PROXY") == "true" {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/gorilla/mux"
+)
+
+// AddCommentReaction handles reacting to a comment with one of the whitelisted
+// emoji. Reacting twice with the same emoji is a no-op.
+func AddCommentReaction(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	comment, ok := findVisibleRouteComment(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Emoji string `json:"emoji"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !isReactionEmoji(req.Emoji) {
+		http.Error(w, "Unsupported reaction", http.StatusBadRequest)
+		return
+	}
+
+	reaction := models.CommentReaction{CommentID: comment.ID, UserID: userID, Emoji: req.Emoji}
+	err = database.GetDB().Where(reaction).FirstOrCreate(&reaction).Error
+	if err != nil {
+		http.Error(w, "Failed to add reaction", http.StatusInternalServerError)
+		return
+	}
+	attachCommentReactions(userID, &comment)
+
+	// Set appropriate response status and return the comment with its reactions
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// RemoveCommentReaction handles removing the caller's reaction with the emoji in the route
+func RemoveCommentReaction(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	comment, ok := findVisibleRouteComment(w, r, userID)
+	if !ok {
+		return
+	}
+
+	result := database.GetDB().Where("comment_id = ? AND user_id = ? AND emoji = ?", comment.ID, userID, mux.Vars(r)["emoji"]).
+		Delete(models.CommentReaction{})
+	if result.Error != nil {
+		http.Error(w, "Failed to remove reaction", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Reaction not found", http.StatusNotFound)
+		return
+	}
+	attachCommentReactions(userID, &comment)
+
+	// Set appropriate response status and return the comment with its reactions
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// findVisibleRouteComment loads the comment referenced by the commentID route
+// variable if the viewer may see it
+func findVisibleRouteComment(w http.ResponseWriter, r *http.Request, viewerID uint) (models.Comment, bool) {
+	var comment models.Comment
+
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentID"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return comment, false
+	}
+
+	err = database.GetDB().First(&comment, commentID).Error
+	if err != nil || !canViewComment(viewerID, comment) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return comment, false
+	}
+
+	return comment, true
+}
+
+// isReactionEmoji reports whether the emoji is on the reaction whitelist
+func isReactionEmoji(emoji string) bool {
+	for _, allowed := range models.ReactionEmojis {
+		if emoji == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// attachReactions loads the reaction counts per emoji and the viewer's own
+// reactions for the comments, with one query each
+func attachReactions(viewerID uint, comments []models.Comment) {
+	if len(comments) == 0 {
+		return
+	}
+
+	commentIDs := make([]uint, len(comments))
+	index := make(map[uint]int, len(comments))
+	for i, comment := range comments {
+		commentIDs[i] = comment.ID
+		index[comment.ID] = i
+		comments[i].Reactions = map[string]int{}
+		comments[i].MyReactions = []string{}
+	}
+
+	var counts []struct {
+		CommentID uint
+		Emoji     string
+		Count     int
+	}
+	err := database.GetDB().Model(&models.CommentReaction{}).Select("comment_id, emoji, COUNT(*) AS count").
+		Where("comment_id IN (?)", commentIDs).Group("comment_id, emoji").Scan(&counts).Error
+	if err != nil {
+		log.Printf("Failed to load comment reactions: %v", err)
+		return
+	}
+	for _, row := range counts {
+		comments[index[row.CommentID]].Reactions[row.Emoji] = row.Count
+	}
+
+	if viewerID == 0 {
+		return
+	}
+	var mine []models.CommentReaction
+	err = database.GetDB().Where("comment_id IN (?) AND user_id = ?", commentIDs, viewerID).Order("id").Find(&mine).Error
+	if err != nil {
+		log.Printf("Failed to load comment reactions: %v", err)
+		return
+	}
+	for _, reaction := range mine {
+		i := index[reaction.CommentID]
+		comments[i].MyReactions = append(comments[i].MyReactions, reaction.Emoji)
+	}
+}
+
+// attachCommentReactions loads the reactions of a single comment
+func attachCommentReactions(viewerID uint, comment *models.Comment) {
+	comments := []models.Comment{*comment}
+	attachReactions(viewerID, comments)
+	comment.Reactions = comments[0].Reactions
+	comment.MyReactions = comments[0].MyReactions
+}
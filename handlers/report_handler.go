@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+// reportReasons lists the reasons a report can be filed for
+var reportReasons = map[string]bool{
+	"spam":                  true,
+	"harassment":            true,
+	"hate_speech":           true,
+	"nudity":                true,
+	"violence":              true,
+	"impersonation":         true,
+	"intellectual_property": true,
+	"other":                 true,
+}
+
+// CreateReport handles reporting a photo, comment or user to the moderators
+func CreateReport(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TargetType string `json:"target_type"`
+		TargetID   uint   `json:"target_id"`
+		Reason     string `json:"reason"`
+		Details    string `json:"details"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report := models.Report{
+		ReporterID: userID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+		Status:     models.ReportOpen,
+	}
+	if !reportReasons[report.Reason] {
+		http.Error(w, "Invalid report reason", http.StatusBadRequest)
+		return
+	}
+
+	// The reported content has to exist and be visible to the reporter
+	if !canReport(userID, report.TargetType, report.TargetID) {
+		http.Error(w, "Reported content not found", http.StatusNotFound)
+		return
+	}
+
+	// Save report to the database
+	err = database.GetDB().Create(&report).Error
+	if err != nil {
+		http.Error(w, "Failed to create report", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the created report
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// canReport reports whether the target exists and the reporter may see it
+func canReport(reporterID uint, targetType string, targetID uint) bool {
+	switch targetType {
+	case models.ReportPhoto:
+		var photo models.Photo
+		if err := database.GetDB().First(&photo, targetID).Error; err != nil {
+			return false
+		}
+		return photo.UserID != reporterID && canViewPhoto(reporterID, photo)
+	case models.ReportComment:
+		var comment models.Comment
+		if err := database.GetDB().First(&comment, targetID).Error; err != nil {
+			return false
+		}
+		return comment.UserID != reporterID && canViewComment(reporterID, comment)
+	case models.ReportUser:
+		var user models.User
+		if err := database.GetDB().Select("id").First(&user, targetID).Error; err != nil {
+			return false
+		}
+		return user.ID != reporterID
+	default:
+		return false
+	}
+}
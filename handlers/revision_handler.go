@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/gorilla/mux"
+)
+
+// GetPhotoRevisions handles fetching the earlier titles and captions of a photo,
+// newest first, for anyone who may see the photo
+func GetPhotoRevisions(w http.ResponseWriter, r *http.Request) {
+	photoID, err := strconv.Atoi(mux.Vars(r)["photoID"])
+	if err != nil {
+		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
+		return
+	}
+
+	var photo models.Photo
+	err = database.GetDB().First(&photo, photoID).Error
+	if err != nil || !canViewPhoto(getOptionalUserID(r), photo) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+
+	revisions := []models.PhotoRevision{}
+	err = database.GetDB().Where("photo_id = ?", photo.ID).Order("created_at DESC, id DESC").Find(&revisions).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch revisions", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched revisions
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// GetCommentRevisions handles fetching the earlier messages of a comment, newest
+// first, for anyone who may see the comment
+func GetCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentID"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var comment models.Comment
+	err = database.GetDB().First(&comment, commentID).Error
+	if err != nil || !canViewComment(getOptionalUserID(r), comment) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+
+	revisions := []models.CommentRevision{}
+	err = database.GetDB().Where("comment_id = ?", comment.ID).Order("created_at DESC, id DESC").Find(&revisions).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch revisions", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched revisions
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/listing"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/search"
+)
+
+// SearchResult is one ranked hit with the matching user, photo or comment
+type SearchResult struct {
+	Type    string          `json:"type"`
+	Score   float64         `json:"score"`
+	User    *models.User    `json:"user,omitempty"`
+	Photo   *models.Photo   `json:"photo,omitempty"`
+	Comment *models.Comment `json:"comment,omitempty"`
+}
+
+// Search handles GET /search?q= across usernames, photo titles and captions and
+// comment messages. Results are ranked, terms match as prefixes and only content
+// the caller may see is returned.
+func Search(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	text := strings.TrimSpace(values.Get("q"))
+	if text == "" {
+		http.Error(w, "Missing search query", http.StatusBadRequest)
+		return
+	}
+
+	types := search.AllTypes
+	if raw := values.Get("type"); raw != "" {
+		types = strings.Split(raw, ",")
+	}
+
+	limit, err := listing.ParseLimit(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The search cursor is the offset into the ranked hits
+	offset, err := listing.ParseOffsetCursor(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewerID := getOptionalUserID(r)
+	query := search.Query{Text: text, Types: types, Filters: searchFilters(viewerID)}
+
+	// Hits the viewer may not see are dropped, so keep reading hits until the page
+	// is full. The cursor is the position of the first hit not returned yet.
+	page := listing.Page[SearchResult]{Data: []SearchResult{}}
+	position := offset
+	for round := 0; page.NextCursor == "" && round < maxSearchRounds; round++ {
+		query.Offset, query.Limit = position, limit+1
+		hits, err := search.GetIndex().Search(query)
+		if err != nil {
+			http.Error(w, "Failed to search", http.StatusInternalServerError)
+			return
+		}
+
+		for i, result := range loadSearchResults(viewerID, hits) {
+			if result == nil {
+				continue
+			}
+			if len(page.Data) == limit {
+				page.NextCursor = listing.OffsetCursor(position + i)
+				break
+			}
+			page.Data = append(page.Data, *result)
+		}
+		if len(hits) <= limit {
+			break
+		}
+		position += len(hits)
+		if round == maxSearchRounds-1 && page.NextCursor == "" {
+			page.NextCursor = listing.OffsetCursor(position)
+		}
+	}
+
+	// Set appropriate response status and return the search results
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// maxSearchRounds bounds how many times a search reads more hits to fill a page
+const maxSearchRounds = 5
+
+// searchFilters restricts the hits to what loadSearchResults would return, so the
+// database index does not page through content the viewer may not see
+func searchFilters(viewerID uint) map[string]search.Filter {
+	photoCondition, photoArgs := visiblePhotoCondition(viewerID)
+	commentCondition, commentArgs := visibleCommentCondition(viewerID)
+	return map[string]search.Filter{
+		search.TypeUser:    {Condition: "users.id NOT IN (" + blockedSubquery + ")", Args: []interface{}{viewerID, viewerID}},
+		search.TypePhoto:   {Condition: photoCondition, Args: photoArgs},
+		search.TypeComment: {Condition: "(" + commentCondition + ")", Args: commentArgs},
+	}
+}
+
+// loadSearchResults loads the hits in rank order. The result of a hit the viewer may
+// not see is nil.
+func loadSearchResults(viewerID uint, hits []search.Hit) []*SearchResult {
+	ids := map[string][]uint{}
+	for _, hit := range hits {
+		ids[hit.Type] = append(ids[hit.Type], hit.ID)
+	}
+
+	users := map[uint]models.User{}
+	if len(ids[search.TypeUser]) > 0 {
+		// Private accounts are still found by username, only blocks hide them
+		var rows []models.User
+		database.GetDB().Where("id IN (?)", ids[search.TypeUser]).
+			Where("users.id NOT IN ("+blockedSubquery+")", viewerID, viewerID).Find(&rows)
+		for _, user := range publicUsers(rows) {
+			users[user.ID] = user
+		}
+	}
+
+	photos := map[uint]models.Photo{}
+	if len(ids[search.TypePhoto]) > 0 {
+		var rows []models.Photo
+		condition, args := visiblePhotoCondition(viewerID)
+		database.GetDB().Where("id IN (?) AND status IN (?)", ids[search.TypePhoto], publishedStatuses).
+			Where(condition, args...).Find(&rows)
+		attachVariants(rows)
+		attachMedia(rows)
+		markBookmarked(viewerID, rows)
+		for _, photo := range rows {
+			photos[photo.ID] = photo
+		}
+	}
+
+	comments := map[uint]models.Comment{}
+	if len(ids[search.TypeComment]) > 0 {
+		var rows []models.Comment
+		condition, args := visibleCommentCondition(viewerID)
+		database.GetDB().Where("id IN (?)", ids[search.TypeComment]).Where(condition, args...).Find(&rows)
+		attachReactions(viewerID, rows)
+		for _, comment := range rows {
+			comments[comment.ID] = comment
+		}
+	}
+
+	results := make([]*SearchResult, len(hits))
+	for i, hit := range hits {
+		result := SearchResult{Type: hit.Type, Score: hit.Score}
+		switch hit.Type {
+		case search.TypeUser:
+			user, ok := users[hit.ID]
+			if !ok {
+				continue
+			}
+			result.User = &user
+		case search.TypePhoto:
+			photo, ok := photos[hit.ID]
+			if !ok {
+				continue
+			}
+			result.Photo = &photo
+		case search.TypeComment:
+			comment, ok := comments[hit.ID]
+			if !ok {
+				continue
+			}
+			result.Comment = &comment
+		default:
+			continue
+		}
+		results[i] = &result
+	}
+	return results
+}
@@ -6,7 +6,9 @@ import (
 	"strconv"
 
 	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/listing"
 	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/webhooks"
 	"github.com/gorilla/mux"
 )
 
@@ -36,12 +38,16 @@ func CreateSocialMediaEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Notify webhook subscribers
+	webhooks.Dispatch(webhooks.SocialMediaCreated, socialMedia, userID)
+
 	// Set appropriate response status and return the created social media entry
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(socialMedia)
 }
 
-// GetAllSocialMediaEntries handles fetching all social media entries from the logged-in user
+// GetAllSocialMediaEntries handles fetching all social media entries from the logged-in user,
+// or from the user given in the user_id query parameter
 func GetAllSocialMediaEntries(w http.ResponseWriter, r *http.Request) {
 	// Retrieve user ID from token or request context
 	userID, err := getUserIDFromToken(r)
@@ -50,17 +56,39 @@ func GetAllSocialMediaEntries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ownerID := userID
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		ownerID = uint(id)
+	}
+
+	// Links of private accounts are only visible to approved followers
+	if !canViewUserContent(userID, ownerID) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	query, err := listing.Parse(r.URL.Query(), socialMediaListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var socialMediaEntries []models.SocialMedia
-	err = database.GetDB().Where("user_id = ?", userID).Find(&socialMediaEntries).Error
+	err = database.GetDB().Where("user_id = ?", ownerID).Scopes(query.Scope).Find(&socialMediaEntries).Error
 	if err != nil {
 		http.Error(w, "Failed to fetch social media entries", http.StatusInternalServerError)
 		return
 	}
 
-	// Set appropriate response status and return the fetched social media entries
+	// Set appropriate response status and return the fetched page of social media entries
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(socialMediaEntries)
+	json.NewEncoder(w).Encode(listing.Paginate(query, socialMediaEntries))
 }
 
 // GetSocialMediaEntryByID handles fetching a specific social media entry by its ID from the logged-in user
@@ -134,6 +162,9 @@ func UpdateSocialMediaEntryByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Notify webhook subscribers
+	webhooks.Dispatch(webhooks.SocialMediaUpdated, existingSocialMedia, userID)
+
 	// Set appropriate response status and return the updated social media entry
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(existingSocialMedia)
@@ -170,6 +201,9 @@ func DeleteSocialMediaEntryByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Notify webhook subscribers
+	webhooks.Dispatch(webhooks.SocialMediaDeleted, existingSocialMedia, userID)
+
 	// Set appropriate response status
 	w.WriteHeader(http.StatusOK)
 }
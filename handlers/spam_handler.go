@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+// GetSpamScores handles fetching spam assessments for moderators, highest score first.
+// Results can be narrowed with user_id, action, target_type and min_score.
+func GetSpamScores(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireModerator(w, r); !ok {
+		return
+	}
+
+	query := database.GetDB().Order("score desc, created_at desc").Limit(200)
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if action := r.URL.Query().Get("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if targetType := r.URL.Query().Get("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if raw := r.URL.Query().Get("min_score"); raw != "" {
+		minScore, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid min_score", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("score >= ?", minScore)
+	}
+
+	var scores []models.SpamScore
+	err := query.Find(&scores).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch spam scores", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched scores
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scores)
+}
+
+// UpdateUserVerification handles a moderator marking a user as verified or not.
+// Verified users score lower in the spam heuristics.
+func UpdateUserVerification(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	target, ok := findRouteUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Verified bool   `json:"verified"`
+		Note     string `json:"note"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	action := models.ModerationAction{
+		ModeratorID: moderatorID,
+		TargetType:  models.ReportUser,
+		TargetID:    target.ID,
+		Action:      models.ActionUnverify,
+		Note:        req.Note,
+	}
+	if req.Verified {
+		action.Action = models.ActionVerify
+	}
+
+	tx := database.GetDB().Begin()
+	err = tx.Model(&models.User{}).Where("id = ?", target.ID).UpdateColumn("verified", req.Verified).Error
+	if err == nil {
+		err = tx.Create(&action).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to update verification", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to update verification", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the recorded action
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(action)
+}
+
+// checkCooldown loads the user and rejects the request while a spam cooldown is active
+func checkCooldown(w http.ResponseWriter, userID uint) (models.User, bool) {
+	var user models.User
+	err := database.GetDB().First(&user, userID).Error
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return user, false
+	}
+
+	if user.CooldownUntil != nil && user.CooldownUntil.After(time.Now()) {
+		seconds := int(time.Until(*user.CooldownUntil).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		http.Error(w, fmt.Sprintf("You are posting too fast, try again in %d seconds", seconds), http.StatusTooManyRequests)
+		return user, false
+	}
+
+	return user, true
+}
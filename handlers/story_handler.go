@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/imaging"
+	"github.com/faazabilamri7/mygram/listing"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/storage"
+	"github.com/faazabilamri7/mygram/stories"
+	"github.com/gorilla/mux"
+)
+
+// StoryTrayEntry groups the active stories of one user in the stories tray
+type StoryTrayEntry struct {
+	User      models.User    `json:"user"`
+	Stories   []models.Story `json:"stories"`
+	HasUnseen bool           `json:"has_unseen"`
+}
+
+// StoryViewer is a user who viewed a story and when
+type StoryViewer struct {
+	User     models.User `json:"user"`
+	ViewedAt time.Time   `json:"viewed_at"`
+}
+
+// activeStoryCondition matches stories that have not expired yet
+const activeStoryCondition = "stories.archived_at IS NULL AND stories.expires_at > ?"
+
+// CreateStory handles uploading a story with the image in the "media" field. The
+// story is shown for 24 hours.
+func CreateStory(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	upload, contentType, err := readUploadedImage(w, r, "media")
+	if err != nil {
+		http.Error(w, err.Error(), uploadErrorStatus(err))
+		return
+	}
+
+	// Apply the EXIF orientation and strip all metadata before storing
+	upload, contentType, _, err = imaging.Sanitize(upload, contentType)
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	caption := r.FormValue("caption")
+
+	// Blocked users cannot be mentioned
+	if mentionsBlockedUser(userID, caption) {
+		http.Error(w, "You cannot mention one or more of these users", http.StatusForbidden)
+		return
+	}
+
+	key, err := newObjectKey("stories", userID, contentType)
+	if err != nil {
+		http.Error(w, "Failed to store story", http.StatusInternalServerError)
+		return
+	}
+	err = storage.GetStorage().Put(key, upload, contentType)
+	if err != nil {
+		log.Printf("Failed to store story %s: %v", key, err)
+		http.Error(w, "Failed to store story", http.StatusInternalServerError)
+		return
+	}
+
+	story := models.Story{
+		UserID:     userID,
+		Caption:    caption,
+		URL:        storage.GetStorage().URL(key),
+		StorageKey: key,
+		ExpiresAt:  time.Now().Add(stories.Lifetime),
+	}
+
+	// Save story to the database
+	err = database.GetDB().Create(&story).Error
+	if err != nil {
+		storage.GetStorage().Delete(key)
+		http.Error(w, "Failed to create story", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the created story
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(story)
+}
+
+// GetStoryTray handles fetching the active stories of the caller and the users they
+// follow, grouped by user. Users with stories the caller has not seen come first.
+func GetStoryTray(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	notMuted, mutedArgs := notMutedCondition("stories.user_id", userID)
+
+	var active []models.Story
+	err = database.GetDB().Preload("User").
+		Where("stories.user_id = ? OR stories.user_id IN ("+followedSubquery+")", userID, userID, models.FollowAccepted).
+		Where("stories.user_id NOT IN ("+blockedSubquery+")", userID, userID).
+		Where(notMuted, mutedArgs...).
+		Where(activeStoryCondition, time.Now()).
+		Order("stories.created_at").Find(&active).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch stories", http.StatusInternalServerError)
+		return
+	}
+	markSeenStories(userID, active)
+
+	tray := []StoryTrayEntry{}
+	index := map[uint]int{}
+	for _, story := range active {
+		i, ok := index[story.UserID]
+		if !ok {
+			i = len(tray)
+			index[story.UserID] = i
+			tray = append(tray, StoryTrayEntry{User: publicUsers([]models.User{story.User})[0]})
+		}
+		tray[i].Stories = append(tray[i].Stories, story)
+		if !story.Seen && story.UserID != userID {
+			tray[i].HasUnseen = true
+		}
+	}
+
+	// Own stories first, then unseen, then the most recently posted
+	sort.SliceStable(tray, func(i, j int) bool {
+		if (tray[i].User.ID == userID) != (tray[j].User.ID == userID) {
+			return tray[i].User.ID == userID
+		}
+		if tray[i].HasUnseen != tray[j].HasUnseen {
+			return tray[i].HasUnseen
+		}
+		return latestStory(tray[i]).After(latestStory(tray[j]))
+	})
+
+	// Set appropriate response status and return the stories tray
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tray)
+}
+
+// GetStoryByID handles viewing a story and records the view. Expired stories are
+// only visible to their owner.
+func GetStoryByID(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	story, ok := findRouteStory(w, r)
+	if !ok {
+		return
+	}
+
+	if story.UserID != userID {
+		if !storyIsActive(story) || !canViewUserContent(userID, story.UserID) {
+			http.Error(w, "Story not found", http.StatusNotFound)
+			return
+		}
+
+		view := models.StoryView{StoryID: story.ID, ViewerID: userID}
+		err = database.GetDB().Where(models.StoryView{StoryID: story.ID, ViewerID: userID}).FirstOrCreate(&view).Error
+		if err != nil {
+			log.Printf("Failed to record view of story %d: %v", story.ID, err)
+		}
+		story.Seen = true
+	} else {
+		database.GetDB().Model(&models.StoryView{}).Where("story_id = ?", story.ID).Count(&story.ViewCount)
+	}
+
+	// Set appropriate response status and return the fetched story
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(story)
+}
+
+// GetStoryViews handles fetching who viewed one of the caller's stories, latest first
+func GetStoryViews(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	story, ok := findRouteStory(w, r)
+	if !ok {
+		return
+	}
+	if story.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var views []models.StoryView
+	err = database.GetDB().Preload("Viewer").Where("story_id = ?", story.ID).Order("created_at DESC").Find(&views).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch story views", http.StatusInternalServerError)
+		return
+	}
+
+	viewers := []StoryViewer{}
+	for _, view := range views {
+		viewers = append(viewers, StoryViewer{User: publicUsers([]models.User{view.Viewer})[0], ViewedAt: view.CreatedAt})
+	}
+
+	// Set appropriate response status and return the story viewers
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(viewers)
+}
+
+// GetStoryArchive handles fetching the caller's expired stories
+func GetStoryArchive(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query, err := listing.Parse(r.URL.Query(), storyListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var archived []models.Story
+	err = database.GetDB().Where("stories.user_id = ?", userID).
+		Where("stories.archived_at IS NOT NULL OR stories.expires_at <= ?", time.Now()).
+		Scopes(query.Scope).Find(&archived).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch story archive", http.StatusInternalServerError)
+		return
+	}
+	page := listing.Paginate(query, archived)
+	countStoryViews(page.Data)
+
+	// Set appropriate response status and return the fetched page of stories
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// DeleteStoryByID handles permanently deleting one of the caller's stories
+func DeleteStoryByID(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	story, ok := findRouteStory(w, r)
+	if !ok {
+		return
+	}
+	if story.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tx := database.GetDB().Begin()
+	err = tx.Where("story_id = ?", story.ID).Delete(models.StoryView{}).Error
+	if err == nil {
+		err = tx.Delete(&story).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to delete story", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to delete story", http.StatusInternalServerError)
+		return
+	}
+
+	// Remove the uploaded image from storage
+	if err := storage.GetStorage().Delete(story.StorageKey); err != nil {
+		log.Printf("Failed to delete stored story %s: %v", story.StorageKey, err)
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
+
+// findRouteStory loads the story referenced by the storyID route variable
+func findRouteStory(w http.ResponseWriter, r *http.Request) (models.Story, bool) {
+	var story models.Story
+
+	storyID, err := strconv.Atoi(mux.Vars(r)["storyID"])
+	if err != nil {
+		http.Error(w, "Invalid story ID", http.StatusBadRequest)
+		return story, false
+	}
+
+	err = database.GetDB().First(&story, storyID).Error
+	if err != nil {
+		http.Error(w, "Story not found", http.StatusNotFound)
+		return story, false
+	}
+
+	return story, true
+}
+
+// storyIsActive reports whether the story is still shown to other users
+func storyIsActive(story models.Story) bool {
+	return story.ArchivedAt == nil && story.ExpiresAt.After(time.Now())
+}
+
+// latestStory returns when the newest story of a tray entry was posted
+func latestStory(entry StoryTrayEntry) time.Time {
+	return entry.Stories[len(entry.Stories)-1].CreatedAt
+}
+
+// markSeenStories sets Seen on the stories the viewer has viewed
+func markSeenStories(viewerID uint, list []models.Story) {
+	if len(list) == 0 {
+		return
+	}
+
+	storyIDs := make([]uint, len(list))
+	for i, story := range list {
+		storyIDs[i] = story.ID
+	}
+
+	var seen []uint
+	database.GetDB().Model(&models.StoryView{}).
+		Where("viewer_id = ? AND story_id IN (?)", viewerID, storyIDs).Pluck("story_id", &seen)
+	for i := range list {
+		list[i].Seen = containsID(seen, list[i].ID)
+	}
+}
+
+// countStoryViews sets ViewCount on each story
+func countStoryViews(list []models.Story) {
+	if len(list) == 0 {
+		return
+	}
+
+	storyIDs := make([]uint, len(list))
+	for i, story := range list {
+		storyIDs[i] = story.ID
+	}
+
+	var counts []struct {
+		StoryID uint
+		Count   int
+	}
+	database.GetDB().Model(&models.StoryView{}).Select("story_id, COUNT(*) AS count").
+		Where("story_id IN (?)", storyIDs).Group("story_id").Scan(&counts)
+	byStory := map[uint]int{}
+	for _, row := range counts {
+		byStory[row.StoryID] = row.Count
+	}
+	for i := range list {
+		list[i].ViewCount = byStory[list[i].ID]
+	}
+}
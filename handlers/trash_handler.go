@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/search"
+	"github.com/faazabilamri7/mygram/trash"
+	"github.com/faazabilamri7/mygram/webhooks"
+	"github.com/gorilla/mux"
+)
+
+// GetTrash handles fetching the deleted photos and comments of the logged-in user
+// that have not been purged yet
+func GetTrash(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var photos []models.Photo
+	err = database.GetDB().Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at desc").Find(&photos).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch trash", http.StatusInternalServerError)
+		return
+	}
+
+	var comments []models.Comment
+	err = database.GetDB().Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at desc").Find(&comments).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch trash", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the trashed items
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"photos":         photos,
+		"comments":       comments,
+		"retention_days": int(trash.Retention().Hours() / 24),
+	})
+}
+
+// RestorePhotoByID handles restoring a deleted photo from the trash
+func RestorePhotoByID(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	photoID, err := strconv.Atoi(mux.Vars(r)["photoID"])
+	if err != nil {
+		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the photo is in the user's trash
+	var photo models.Photo
+	err = database.GetDB().Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", photoID, userID).
+		First(&photo).Error
+	if err != nil {
+		http.Error(w, "Photo not found in trash", http.StatusNotFound)
+		return
+	}
+
+	err = database.GetDB().Unscoped().Model(&photo).UpdateColumn("deleted_at", nil).Error
+	if err != nil {
+		http.Error(w, "Failed to restore photo", http.StatusInternalServerError)
+		return
+	}
+	photo.DeletedAt = nil
+	attachVariant(&photo)
+	attachMediaItems(&photo)
+
+	// Notify webhook subscribers of restored published photos
+	if isPublished(photo) {
+		webhooks.Dispatch(webhooks.PhotoRestored, photo, userID)
+		search.IndexPhoto(photo)
+	}
+
+	// Set appropriate response status and return the restored photo
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(photo)
+}
+
+// RestoreCommentByID handles restoring a deleted comment from the trash
+func RestoreCommentByID(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentID"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the comment is in the user's trash
+	var comment models.Comment
+	err = database.GetDB().Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", commentID, userID).
+		First(&comment).Error
+	if err != nil {
+		http.Error(w, "Comment not found in trash", http.StatusNotFound)
+		return
+	}
+
+	err = database.GetDB().Unscoped().Model(&comment).UpdateColumn("deleted_at", nil).Error
+	if err != nil {
+		http.Error(w, "Failed to restore comment", http.StatusInternalServerError)
+		return
+	}
+	comment.DeletedAt = nil
+
+	// Notify webhook subscribers of the comment author and the photo owner
+	webhooks.Dispatch(webhooks.CommentRestored, comment, commentAudience(comment)...)
+	indexComment(comment)
+
+	// Set appropriate response status and return the restored comment
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(comment)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/faazabilamri7/mygram/imaging"
+)
+
+const defaultMaxUploadBytes = 10 << 20 // 10 MB
+
+// allowedImageTypes maps the sniffed content types accepted for uploads to their file extension
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var (
+	errUploadTooLarge   = errors.New("Uploaded file is too large")
+	errUnsupportedImage = errors.New("Unsupported image type")
+	errMissingUpload    = errors.New("Missing uploaded file")
+	errTooManyUploads   = errors.New("Too many uploaded files")
+)
+
+// isMultipart reports whether the request body is a multipart form
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// maxUploadBytes returns the upload size limit from UPLOAD_MAX_BYTES
+func maxUploadBytes() int64 {
+	if n, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_BYTES"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return defaultMaxUploadBytes
+}
+
+// multipartBodyLimit is the largest multipart body carrying up to files uploads,
+// leaving some room for the other form fields
+func multipartBodyLimit(files int) int64 {
+	return maxUploadBytes()*int64(files) + 1<<20
+}
+
+// uploadedImage is an uploaded image with its sniffed content type
+type uploadedImage struct {
+	Data        []byte
+	ContentType string
+}
+
+// readUploadedImage parses a multipart request and returns the image in the given form field
+// together with its sniffed content type. The client supplied content type is ignored.
+func readUploadedImage(w http.ResponseWriter, r *http.Request, field string) ([]byte, string, error) {
+	images, err := readUploadedImages(w, r, field, 1)
+	if err != nil {
+		return nil, "", err
+	}
+	return images[0].Data, images[0].ContentType, nil
+}
+
+// readUploadedImages parses a multipart request and returns up to max images sent in
+// the given form field, in the order they were sent. Each image is subject to the
+// upload size limit.
+func readUploadedImages(w http.ResponseWriter, r *http.Request, field string, max int) ([]uploadedImage, error) {
+	limit := maxUploadBytes()
+	r.Body = http.MaxBytesReader(w, r.Body, multipartBodyLimit(max))
+	if err := r.ParseMultipartForm(limit); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, errUploadTooLarge
+		}
+		return nil, err
+	}
+
+	headers := r.MultipartForm.File[field]
+	if len(headers) == 0 {
+		return nil, errMissingUpload
+	}
+	if len(headers) > max {
+		return nil, errTooManyUploads
+	}
+
+	images := make([]uploadedImage, len(headers))
+	for i, header := range headers {
+		data, contentType, err := readImagePart(header, limit)
+		if err != nil {
+			return nil, err
+		}
+		images[i] = uploadedImage{Data: data, ContentType: contentType}
+	}
+	return images, nil
+}
+
+// readImagePart reads one uploaded file and checks its size and sniffed content type
+func readImagePart(header *multipart.FileHeader, limit int64) ([]byte, string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, "", errMissingUpload
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > limit {
+		return nil, "", errUploadTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := allowedImageTypes[contentType]; !ok {
+		return nil, "", errUnsupportedImage
+	}
+
+	return data, contentType, nil
+}
+
+// newObjectKey builds a random storage key such as "photos/7/3f2a...9c.jpg"
+func newObjectKey(prefix string, userID uint, contentType string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%d/%s%s", strings.Trim(prefix, "/"), userID, hex.EncodeToString(buf), allowedImageTypes[contentType]), nil
+}
+
+// writeImageError responds to an uploaded image that could not be processed
+func writeImageError(w http.ResponseWriter, err error) {
+	if err == imaging.ErrImageTooLarge {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, "Invalid image", http.StatusBadRequest)
+}
+
+// uploadErrorStatus maps upload errors to a response status
+func uploadErrorStatus(err error) int {
+	switch err {
+	case errUploadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case errUnsupportedImage:
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusBadRequest
+	}
+}
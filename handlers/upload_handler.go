@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/storage"
+)
+
+// ServeUpload returns a handler serving locally stored objects below prefix. Each
+// object is resolved to the photo or story it belongs to, and only served to viewers
+// who may see it. Directories are never listed.
+func ServeUpload(prefix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, prefix+"/")
+		if key == "" || strings.HasSuffix(key, "/") || key != path.Clean(key) {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+
+		viewerID := getOptionalUserID(r)
+		if !canViewUpload(viewerID, key) {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+
+		data, err := storage.GetStorage().Get(key)
+		if err != nil {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+
+		// Objects can be restricted, so shared caches must not keep them
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, path.Base(key), time.Time{}, bytes.NewReader(data))
+	}
+}
+
+// canViewUpload reports whether the viewer may see the stored object: the original,
+// a media item or a variant of a photo they can view, or a story they can view
+func canViewUpload(viewerID uint, key string) bool {
+	db := database.GetDB()
+
+	// Trashed photos are loaded too, their files are only served to the owner
+	var photo models.Photo
+	if db.Unscoped().Where("storage_key = ?", key).First(&photo).Error == nil {
+		return canViewStoredPhoto(viewerID, photo)
+	}
+
+	var media models.PhotoMedia
+	if db.Where("storage_key = ?", key).First(&media).Error == nil {
+		return db.Unscoped().First(&photo, media.PhotoID).Error == nil && canViewStoredPhoto(viewerID, photo)
+	}
+
+	var variant models.PhotoVariant
+	if db.Where("storage_key = ?", key).First(&variant).Error == nil {
+		return db.Unscoped().First(&photo, variant.PhotoID).Error == nil && canViewStoredPhoto(viewerID, photo)
+	}
+
+	var story models.Story
+	if db.Where("storage_key = ?", key).First(&story).Error == nil {
+		if viewerID != 0 && story.UserID == viewerID {
+			return true
+		}
+		return storyIsActive(story) && canViewUserContent(viewerID, story.UserID)
+	}
+
+	return false
+}
+
+// canViewStoredPhoto reports whether the viewer may see the files of a photo that
+// may be in the trash
+func canViewStoredPhoto(viewerID uint, photo models.Photo) bool {
+	if photo.DeletedAt != nil && photo.UserID != viewerID {
+		return false
+	}
+	return canViewPhoto(viewerID, photo)
+}
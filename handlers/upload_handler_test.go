@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faazabilamri7/mygram/models"
+)
+
+func TestCanViewStoredPhotoHidesTrash(t *testing.T) {
+	deleted := time.Now()
+	photo := models.Photo{UserID: 1, Visibility: models.PhotoPublic, DeletedAt: &deleted}
+
+	for _, viewerID := range []uint{0, 2} {
+		if canViewStoredPhoto(viewerID, photo) {
+			t.Errorf("viewer %d can see the files of a trashed photo", viewerID)
+		}
+	}
+	if !canViewStoredPhoto(1, photo) {
+		t.Error("the owner should still see the files of their trashed photo")
+	}
+}
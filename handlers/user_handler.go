@@ -7,6 +7,7 @@ import (
 
 	"github.com/faazabilamri7/mygram/database"
 	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/search"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -27,6 +28,13 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 	}
 	user.Password = string(hashedPassword)
 
+	// Moderation fields are never set by the client
+	user.Role = models.RoleUser
+	user.WarningCount = 0
+	user.SuspendedUntil = nil
+	user.Verified = false
+	user.CooldownUntil = nil
+
 	// Set created and updated timestamps
 	currentTime := time.Now()
 	user.CreatedAt = currentTime
@@ -38,6 +46,7 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to register user", http.StatusInternalServerError)
 		return
 	}
+	search.IndexUser(user)
 
 	// Set appropriate response status and return the registered user
 	w.WriteHeader(http.StatusCreated)
@@ -71,6 +80,12 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Suspended users cannot log in
+	if isSuspended(user) {
+		http.Error(w, "Account is suspended", http.StatusForbidden)
+		return
+	}
+
 	// Generate JWT token
 	token, err := generateToken(int64(user.ID), user.Email)
 	if err != nil {
@@ -88,7 +103,12 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 
 // UpdateUser handles updating user information
 func UpdateUser(w http.ResponseWriter, r *http.Request) {
-	var updateUserReq models.User
+	// Settings left out of the request keep their value
+	var updateUserReq struct {
+		models.User
+		KeepPhotoMetadata *bool `json:"keep_photo_metadata"`
+		IsPrivate         *bool `json:"is_private"`
+	}
 	err := json.NewDecoder(r.Body).Decode(&updateUserReq)
 	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -115,6 +135,13 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 	user.Email = updateUserReq.Email
 	user.Age = updateUserReq.Age
 	user.ImageURL = updateUserReq.ImageURL
+	if updateUserReq.KeepPhotoMetadata != nil {
+		user.KeepPhotoMetadata = *updateUserReq.KeepPhotoMetadata
+	}
+	wasPrivate := user.IsPrivate
+	if updateUserReq.IsPrivate != nil {
+		user.IsPrivate = *updateUserReq.IsPrivate
+	}
 
 	// Save updated user to the database
 	err = database.GetDB().Save(&user).Error
@@ -122,6 +149,14 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to update user", http.StatusInternalServerError)
 		return
 	}
+	search.IndexUser(user)
+
+	// Making the account public approves every pending follow request
+	if wasPrivate && !user.IsPrivate {
+		database.GetDB().Model(&models.Follow{}).
+			Where("followee_id = ? AND status = ?", user.ID, models.FollowPending).
+			Update("status", models.FollowAccepted)
+	}
 
 	// Return updated user as response
 	w.WriteHeader(http.StatusOK)
@@ -151,6 +186,7 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
 		return
 	}
+	search.Remove(search.TypeUser, user.ID)
 
 	// Return success message
 	w.WriteHeader(http.StatusOK)
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
 )
 
 var secretKey = []byte(os.Getenv("SECRET_KEY"))
@@ -35,6 +37,26 @@ func generateToken(userID int64, userEmail string) (string, error) {
 
 // getUserIDFromToken adalah fungsi untuk mendapatkan user ID dari token JWT
 func getUserIDFromToken(r *http.Request) (uint, error) {
+	userID, err := parseTokenUserID(r)
+	if err != nil {
+		return 0, err
+	}
+
+	// Akun yang sedang disuspend tidak boleh memakai token
+	var user models.User
+	err = database.GetDB().Select("id, suspended_until").First(&user, userID).Error
+	if err != nil {
+		return 0, errors.New("User not found")
+	}
+	if isSuspended(user) {
+		return 0, errors.New("Account is suspended")
+	}
+
+	return userID, nil
+}
+
+// parseTokenUserID memverifikasi token JWT dan mengembalikan user ID di dalamnya tanpa membaca database
+func parseTokenUserID(r *http.Request) (uint, error) {
 	// Ambil token JWT dari header Authorization
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
@@ -42,7 +64,11 @@ func getUserIDFromToken(r *http.Request) (uint, error) {
 	}
 
 	// Split token dari header
-	tokenString := strings.Split(authHeader, " ")[1]
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 {
+		return 0, errors.New("Invalid authorization header")
+	}
+	tokenString := parts[1]
 
 	// Parse dan verifikasi token JWT
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -72,6 +98,22 @@ func getUserIDFromToken(r *http.Request) (uint, error) {
 		return 0, errors.New("Invalid user ID format in token claims")
 	}
 
-	userID := uint(userIDFloat)
-	return userID, nil
+	return uint(userIDFloat), nil
+}
+
+// isSuspended memeriksa apakah user sedang disuspend oleh moderator
+func isSuspended(user models.User) bool {
+	return user.SuspendedUntil != nil && user.SuspendedUntil.After(time.Now())
+}
+
+// getOptionalUserID mengembalikan user ID jika request membawa token yang valid, atau 0 jika tidak
+func getOptionalUserID(r *http.Request) uint {
+	if r.Header.Get("Authorization") == "" {
+		return 0
+	}
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		return 0
+	}
+	return userID
 }
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+// normalizePhotoVisibility validates a visibility value, defaulting to public
+func normalizePhotoVisibility(visibility string) (string, bool) {
+	switch visibility {
+	case "":
+		return models.PhotoPublic, true
+	case models.PhotoPublic, models.PhotoFollowers, models.PhotoPrivate:
+		return visibility, true
+	default:
+		return "", false
+	}
+}
+
+// publishedStatuses are the photo statuses of published photos; rows created
+// before drafts existed have an empty status
+var publishedStatuses = []string{models.PhotoPublished, ""}
+
+// isPublished reports whether the photo is published rather than a draft or scheduled
+func isPublished(photo models.Photo) bool {
+	return photo.Status == "" || photo.Status == models.PhotoPublished
+}
+
+// normalizePhotoStatus validates a publishing status. A publish time without a
+// status schedules the photo, and scheduled photos need a publish time in the future.
+func normalizePhotoStatus(status string, publishAt *time.Time) (string, *time.Time, error) {
+	switch status {
+	case "":
+		if publishAt != nil {
+			return normalizePhotoStatus(models.PhotoScheduled, publishAt)
+		}
+		return models.PhotoPublished, nil, nil
+	case models.PhotoPublished, models.PhotoDraft:
+		return status, nil, nil
+	case models.PhotoScheduled:
+		if publishAt == nil || !publishAt.After(time.Now()) {
+			return "", nil, errors.New("Scheduled photos need a publish_at time in the future")
+		}
+		return status, publishAt, nil
+	default:
+		return "", nil, errors.New("Invalid photo status")
+	}
+}
+
+// followedSubquery selects the users the viewer is an approved follower of
+const followedSubquery = "SELECT followee_id FROM follows WHERE follower_id = ? AND status = ?"
+
+// privateUsersSubquery selects users with a private account
+const privateUsersSubquery = "SELECT id FROM users WHERE is_private = ?"
+
+// blockedSubquery selects users the viewer blocked or was blocked by
+const blockedSubquery = "SELECT blocker_id FROM blocks WHERE blocked_id = ? UNION SELECT blocked_id FROM blocks WHERE blocker_id = ?"
+
+// mutedSubquery selects users the viewer muted
+const mutedSubquery = "SELECT muted_id FROM mutes WHERE muter_id = ?"
+
+// visiblePhotoCondition returns a condition on the photos table matching the photos
+// the viewer may see. A viewerID of 0 means an anonymous viewer.
+//
+// Owners see all their photos. Approved followers see everything but private photos.
+// Everybody else sees public photos of public accounts. Photos are never visible
+// between users where one blocked the other, and photos hidden by a moderator,
+// drafts and scheduled photos are only visible to their owner.
+func visiblePhotoCondition(viewerID uint) (string, []interface{}) {
+	condition := "(photos.user_id = ?" +
+		" OR (photos.visibility <> ? AND photos.user_id IN (" + followedSubquery + "))" +
+		" OR (photos.visibility IN (?) AND photos.user_id NOT IN (" + privateUsersSubquery + ")))" +
+		" AND photos.user_id NOT IN (" + blockedSubquery + ")" +
+		" AND (photos.hidden = ? OR photos.user_id = ?)" +
+		" AND (photos.status IN (?) OR photos.user_id = ?)"
+	args := []interface{}{
+		viewerID,
+		models.PhotoPrivate, viewerID, models.FollowAccepted,
+		[]string{models.PhotoPublic, ""}, true,
+		viewerID, viewerID,
+		false, viewerID,
+		publishedStatuses, viewerID,
+	}
+	return "(" + condition + ")", args
+}
+
+// visibleUserCondition returns a condition matching rows whose column refers to a user
+// whose content the viewer may see
+func visibleUserCondition(column string, viewerID uint) (string, []interface{}) {
+	condition := "(" + column + " = ?" +
+		" OR " + column + " NOT IN (" + privateUsersSubquery + ")" +
+		" OR " + column + " IN (" + followedSubquery + "))" +
+		" AND " + column + " NOT IN (" + blockedSubquery + ")"
+	args := []interface{}{viewerID, true, viewerID, models.FollowAccepted, viewerID, viewerID}
+	return "(" + condition + ")", args
+}
+
+// notMutedCondition returns a condition matching rows whose column does not refer to a
+// user the viewer muted. Used for listings only; muted content stays reachable by ID.
+func notMutedCondition(column string, viewerID uint) (string, []interface{}) {
+	return column + " NOT IN (" + mutedSubquery + ")", []interface{}{viewerID}
+}
+
+// visibleCommentCondition returns a condition on the comments table matching comments
+// the viewer may see: the photo has to be visible and so does the comment author.
+// Comments hidden by a moderator or shadow-hidden as spam are only visible to their author.
+func visibleCommentCondition(viewerID uint) (string, []interface{}) {
+	photoCondition, args := visiblePhotoCondition(viewerID)
+	authorCondition, authorArgs := visibleUserCondition("comments.user_id", viewerID)
+	condition := "comments.photo_id IN (SELECT photos.id FROM photos WHERE photos.deleted_at IS NULL AND " + photoCondition + ") AND " + authorCondition +
+		" AND ((comments.hidden = ? AND comments.shadow_hidden = ?) OR comments.user_id = ?)"
+	args = append(args, authorArgs...)
+	return condition, append(args, false, false, viewerID)
+}
+
+// canViewUserContent reports whether the viewer may see content owned by ownerID,
+// which is restricted for private accounts and between blocked users
+func canViewUserContent(viewerID, ownerID uint) bool {
+	if viewerID != 0 && viewerID == ownerID {
+		return true
+	}
+	if isBlockedBetween(viewerID, ownerID) {
+		return false
+	}
+	var owner models.User
+	err := database.GetDB().Select("id, is_private").First(&owner, ownerID).Error
+	if err != nil {
+		return false
+	}
+	return !owner.IsPrivate || isFollowing(viewerID, ownerID)
+}
+
+// canViewPhoto reports whether the viewer may see the photo
+func canViewPhoto(viewerID uint, photo models.Photo) bool {
+	if viewerID != 0 && photo.UserID == viewerID {
+		return true
+	}
+	if photo.Hidden || !isPublished(photo) {
+		return false
+	}
+	switch photo.Visibility {
+	case "", models.PhotoPublic:
+		return canViewUserContent(viewerID, photo.UserID)
+	case models.PhotoFollowers:
+		return isFollowing(viewerID, photo.UserID) && !isBlockedBetween(viewerID, photo.UserID)
+	default:
+		return false
+	}
+}
+
+// canViewComment reports whether the viewer may see the comment
+func canViewComment(viewerID uint, comment models.Comment) bool {
+	if (comment.Hidden || comment.ShadowHidden) && comment.UserID != viewerID {
+		return false
+	}
+	var photo models.Photo
+	err := database.GetDB().First(&photo, comment.PhotoID).Error
+	if err != nil {
+		return false
+	}
+	return canViewPhoto(viewerID, photo) && canViewUserContent(viewerID, comment.UserID)
+}
+
+// isFollowing reports whether followerID is an approved follower of followeeID
+func isFollowing(followerID, followeeID uint) bool {
+	if followerID == 0 {
+		return false
+	}
+	var count int
+	database.GetDB().Model(&models.Follow{}).
+		Where("follower_id = ? AND followee_id = ? AND status = ?", followerID, followeeID, models.FollowAccepted).
+		Count(&count)
+	return count > 0
+}
+
+// isBlockedBetween reports whether either user blocked the other
+func isBlockedBetween(userID, otherID uint) bool {
+	if userID == 0 || otherID == 0 {
+		return false
+	}
+	var count int
+	database.GetDB().Model(&models.Block{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userID, otherID, otherID, userID).
+		Count(&count)
+	return count > 0
+}
+
+// hasMuted reports whether muterID muted mutedID
+func hasMuted(muterID, mutedID uint) bool {
+	var count int
+	database.GetDB().Model(&models.Mute{}).Where("muter_id = ? AND muted_id = ?", muterID, mutedID).Count(&count)
+	return count > 0
+}
+
+var mentionPattern = regexp.MustCompile(`@([A-Za-z0-9_.]+)`)
+
+// mentionsBlockedUser reports whether text mentions a user that blocked the author or
+// was blocked by them
+func mentionsBlockedUser(authorID uint, text string) bool {
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		usernames = append(usernames, match[1])
+	}
+	if len(usernames) == 0 {
+		return false
+	}
+
+	var count int
+	database.GetDB().Model(&models.User{}).
+		Where("username IN (?) AND id IN ("+blockedSubquery+")", usernames, authorID, authorID).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/webhooks"
+	"github.com/gorilla/mux"
+)
+
+// CreateWebhook handles the creation of a new webhook subscription for the logged-in user
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var webhook models.Webhook
+	err = json.NewDecoder(r.Body).Decode(&webhook)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate target URL and subscribed events
+	if !isValidWebhookURL(webhook.URL) {
+		http.Error(w, "Invalid webhook URL", http.StatusBadRequest)
+		return
+	}
+	events, ok := webhooks.NormalizeEvents(webhook.Events)
+	if !ok {
+		http.Error(w, "Invalid webhook events", http.StatusBadRequest)
+		return
+	}
+
+	// Generate the signing secret
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		http.Error(w, "Failed to generate webhook secret", http.StatusInternalServerError)
+		return
+	}
+
+	webhook.ID = 0
+	webhook.UserID = userID
+	webhook.Events = events
+	webhook.Secret = secret
+	webhook.Active = true
+
+	// Save webhook to the database
+	err = database.GetDB().Create(&webhook).Error
+	if err != nil {
+		http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the created webhook
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// GetAllWebhooks handles fetching all webhooks of the logged-in user
+func GetAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var hooks []models.Webhook
+	err = database.GetDB().Where("user_id = ?", userID).Find(&hooks).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched webhooks
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(hooks)
+}
+
+// GetWebhookByID handles fetching a webhook of the logged-in user by its ID
+func GetWebhookByID(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := findOwnedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	// Set appropriate response status and return the fetched webhook
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// UpdateWebhookByID handles updating a webhook by its ID
+func UpdateWebhookByID(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := findOwnedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	// An omitted active flag keeps the current one
+	var updatedWebhook struct {
+		models.Webhook
+		Active *bool `json:"active"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&updatedWebhook)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidWebhookURL(updatedWebhook.URL) {
+		http.Error(w, "Invalid webhook URL", http.StatusBadRequest)
+		return
+	}
+	events, ok := webhooks.NormalizeEvents(updatedWebhook.Events)
+	if !ok {
+		http.Error(w, "Invalid webhook events", http.StatusBadRequest)
+		return
+	}
+
+	// Update webhook fields
+	webhook.Name = updatedWebhook.Name
+	webhook.URL = updatedWebhook.URL
+	webhook.Events = events
+	if updatedWebhook.Active != nil {
+		webhook.Active = *updatedWebhook.Active
+	}
+
+	// Save updated webhook to the database
+	err = database.GetDB().Save(&webhook).Error
+	if err != nil {
+		http.Error(w, "Failed to update webhook", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the updated webhook
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// DeleteWebhookByID handles deleting a webhook and its delivery log by its ID
+func DeleteWebhookByID(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := findOwnedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	// Delete the webhook and its queued deliveries from the database
+	tx := database.GetDB().Begin()
+	err := tx.Where("delivery_id IN (?)", tx.Table("webhook_deliveries").Select("id").Where("webhook_id = ?", webhook.ID).SubQuery()).
+		Delete(models.WebhookAttempt{}).Error
+	if err == nil {
+		err = tx.Where("webhook_id = ?", webhook.ID).Delete(models.WebhookDelivery{}).Error
+	}
+	if err == nil {
+		err = tx.Delete(&webhook).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetWebhookDeliveries handles fetching the delivery log of a webhook
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := findOwnedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := database.GetDB().Where("webhook_id = ?", webhook.ID).Order("id desc").Limit(100).Find(&deliveries).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched deliveries
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// GetWebhookDeliveryAttempts handles fetching the individual attempts of a delivery
+func GetWebhookDeliveryAttempts(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := findOwnedDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	var attempts []models.WebhookAttempt
+	err := database.GetDB().Where("delivery_id = ?", delivery.ID).Order("id").Find(&attempts).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch delivery attempts", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the fetched attempts
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(attempts)
+}
+
+// RedeliverWebhookDelivery handles queueing a delivery to be sent again
+func RedeliverWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := findOwnedDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	// Reset the delivery so the worker picks it up with a fresh retry budget
+	delivery.Status = webhooks.StatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.DeliveredAt = nil
+
+	err := database.GetDB().Save(&delivery).Error
+	if err != nil {
+		http.Error(w, "Failed to redeliver webhook", http.StatusInternalServerError)
+		return
+	}
+
+	// Set appropriate response status and return the queued delivery
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// findOwnedWebhook loads the webhook from the route and writes an error response if the caller does not own it
+func findOwnedWebhook(w http.ResponseWriter, r *http.Request) (models.Webhook, bool) {
+	var webhook models.Webhook
+
+	// Retrieve user ID from token or request context
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return webhook, false
+	}
+
+	webhookID, err := strconv.Atoi(mux.Vars(r)["webhookID"])
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return webhook, false
+	}
+
+	err = database.GetDB().Where("user_id = ? AND id = ?", userID, webhookID).First(&webhook).Error
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return webhook, false
+	}
+
+	return webhook, true
+}
+
+// findOwnedDelivery loads the delivery from the route, scoped to a webhook owned by the caller
+func findOwnedDelivery(w http.ResponseWriter, r *http.Request) (models.WebhookDelivery, bool) {
+	var delivery models.WebhookDelivery
+
+	webhook, ok := findOwnedWebhook(w, r)
+	if !ok {
+		return delivery, false
+	}
+
+	deliveryID, err := strconv.Atoi(mux.Vars(r)["deliveryID"])
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return delivery, false
+	}
+
+	err = database.GetDB().Where("webhook_id = ? AND id = ?", webhook.ID, deliveryID).First(&delivery).Error
+	if err != nil {
+		http.Error(w, "Webhook delivery not found", http.StatusNotFound)
+		return delivery, false
+	}
+
+	return delivery, true
+}
+
+// isValidWebhookURL accepts http(s) URLs that do not point at internal addresses
+func isValidWebhookURL(raw string) bool {
+	return webhooks.ValidateURL(raw) == nil
+}
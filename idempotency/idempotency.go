@@ -0,0 +1,142 @@
+// idempotency/idempotency.go
+package idempotency
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	defaultTTLHours = 24
+	purgeInterval   = time.Hour
+
+	// staleAfter is when a request still in progress is assumed to have died with
+	// its server, freeing the key for a retry
+	staleAfter = 5 * time.Minute
+)
+
+var (
+	// ErrInProgress is returned while the first request with the key is still being handled
+	ErrInProgress = errors.New("A request with this Idempotency-Key is still in progress")
+
+	// ErrMismatch is returned when the key was used for a different request
+	ErrMismatch = errors.New("Idempotency-Key was already used for a different request")
+)
+
+// TTL returns how long responses are kept for replay, from IDEMPOTENCY_TTL_HOURS
+func TTL() time.Duration {
+	hours := defaultTTLHours
+	if n, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_TTL_HOURS")); err == nil && n > 0 {
+		hours = n
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// Begin claims the key of the user for a request. When the key was used before for
+// the same request, the stored record is returned with completed set and its response
+// should be replayed.
+func Begin(userID uint, key, requestHash string) (record models.IdempotencyKey, completed bool, err error) {
+	now := time.Now()
+	record = models.IdempotencyKey{UserID: userID, Key: key, RequestHash: requestHash, ExpiresAt: now.Add(TTL())}
+	createErr := database.GetDB().Create(&record).Error
+	if createErr == nil {
+		return record, false, nil
+	}
+
+	existing, err := find(userID, key)
+	if gorm.IsRecordNotFoundError(err) {
+		// The key was released between the two queries; the client can retry
+		return record, false, ErrInProgress
+	}
+	if err != nil {
+		return record, false, createErr
+	}
+
+	next, err := resolve(existing, requestHash, now)
+	switch {
+	case err != nil:
+		return existing, false, err
+	case next == reclaim:
+		if err := Release(existing); err != nil {
+			return record, false, err
+		}
+		if err := database.GetDB().Create(&record).Error; err != nil {
+			// Another retry reclaimed the key first
+			if _, findErr := find(userID, key); findErr == nil {
+				return record, false, ErrInProgress
+			}
+			return record, false, err
+		}
+		return record, false, nil
+	}
+	return existing, true, nil
+}
+
+// find loads the record holding the key of the user
+func find(userID uint, key string) (models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := database.GetDB().Where("user_id = ? AND idempotency_key = ?", userID, key).First(&record).Error
+	return record, err
+}
+
+// outcome is what a request does with the record already holding its key
+type outcome int
+
+const (
+	replay  outcome = iota // the first request completed; replay its response
+	reclaim                // the key is free again; claim it for this request
+)
+
+// resolve decides how a request is handled when its key is already taken. Expired
+// keys and requests abandoned mid-way can be claimed again.
+func resolve(existing models.IdempotencyKey, requestHash string, now time.Time) (outcome, error) {
+	abandoned := existing.StatusCode == 0 && now.Sub(existing.CreatedAt) > staleAfter
+	if existing.ExpiresAt.Before(now) || abandoned {
+		return reclaim, nil
+	}
+	if existing.RequestHash != requestHash {
+		return replay, ErrMismatch
+	}
+	if existing.StatusCode == 0 {
+		return replay, ErrInProgress
+	}
+	return replay, nil
+}
+
+// Complete stores the response to the request that claimed the key, with the headers
+// that are replayed
+func Complete(record models.IdempotencyKey, statusCode int, header http.Header, body string) error {
+	return database.GetDB().Model(&record).UpdateColumns(map[string]interface{}{
+		"status_code":  statusCode,
+		"content_type": header.Get("Content-Type"),
+		"location":     header.Get("Location"),
+		"retry_after":  header.Get("Retry-After"),
+		"body":         body,
+	}).Error
+}
+
+// Release frees the key so the request can be retried
+func Release(record models.IdempotencyKey) error {
+	return database.GetDB().Delete(&record).Error
+}
+
+// StartPurger periodically deletes expired keys until the process exits
+func StartPurger() {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		err := database.GetDB().Where("expires_at < ?", time.Now()).Delete(models.IdempotencyKey{}).Error
+		if err != nil {
+			log.Printf("idempotency: failed to purge expired keys: %v", err)
+		}
+		<-ticker.C
+	}
+}
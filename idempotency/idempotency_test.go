@@ -0,0 +1,62 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faazabilamri7/mygram/models"
+)
+
+func TestResolve(t *testing.T) {
+	now := time.Now()
+	inProgress := models.IdempotencyKey{RequestHash: "a", CreatedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)}
+	completed := inProgress
+	completed.StatusCode = 201
+
+	tests := []struct {
+		name     string
+		existing models.IdempotencyKey
+		hash     string
+		want     outcome
+		wantErr  error
+	}{
+		{"completed, same request", completed, "a", replay, nil},
+		{"completed, other request", completed, "b", replay, ErrMismatch},
+		{"in progress, same request", inProgress, "a", replay, ErrInProgress},
+		{"in progress, other request", inProgress, "b", replay, ErrMismatch},
+		{"expired", withExpiry(completed, now.Add(-time.Second)), "b", reclaim, nil},
+		{"abandoned", withCreated(inProgress, now.Add(-staleAfter-time.Second)), "a", reclaim, nil},
+		{"slow but completed", withCreated(completed, now.Add(-staleAfter-time.Second)), "a", replay, nil},
+	}
+	for _, tt := range tests {
+		got, err := resolve(tt.existing, tt.hash, now)
+		if err != tt.wantErr || (err == nil && got != tt.want) {
+			t.Errorf("%s: got %v, %v; want %v, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func withExpiry(record models.IdempotencyKey, expiresAt time.Time) models.IdempotencyKey {
+	record.ExpiresAt = expiresAt
+	return record
+}
+
+func withCreated(record models.IdempotencyKey, createdAt time.Time) models.IdempotencyKey {
+	record.CreatedAt = createdAt
+	return record
+}
+
+func TestTTL(t *testing.T) {
+	t.Setenv("IDEMPOTENCY_TTL_HOURS", "")
+	if got := TTL(); got != defaultTTLHours*time.Hour {
+		t.Errorf("default TTL = %v", got)
+	}
+	t.Setenv("IDEMPOTENCY_TTL_HOURS", "2")
+	if got := TTL(); got != 2*time.Hour {
+		t.Errorf("TTL = %v, want 2h", got)
+	}
+	t.Setenv("IDEMPOTENCY_TTL_HOURS", "0")
+	if got := TTL(); got != defaultTTLHours*time.Hour {
+		t.Errorf("zero setting gave %v, want the default", got)
+	}
+}
@@ -0,0 +1,226 @@
+// imaging/exif.go
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// EXIF tags read from uploads
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagDateTimeOriginal = 0x9003
+)
+
+// Metadata is the subset of EXIF data MyGram understands
+type Metadata struct {
+	Orientation int
+	CameraMake  string
+	CameraModel string
+	TakenAt     *time.Time
+}
+
+var errInvalidExif = errors.New("invalid exif data")
+
+// parseExif reads a TIFF structured EXIF blob
+func parseExif(data []byte) (Metadata, error) {
+	meta := Metadata{Orientation: 1}
+	if len(data) < 8 {
+		return meta, errInvalidExif
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return meta, errInvalidExif
+	}
+
+	var dateTime, dateTimeOriginal string
+	visit := func(tag uint16, value func() (uint32, string)) {
+		switch tag {
+		case tagOrientation:
+			n, _ := value()
+			if n >= 1 && n <= 8 {
+				meta.Orientation = int(n)
+			}
+		case tagMake:
+			_, meta.CameraMake = value()
+		case tagModel:
+			_, meta.CameraModel = value()
+		case tagDateTime:
+			_, dateTime = value()
+		case tagDateTimeOriginal:
+			_, dateTimeOriginal = value()
+		}
+	}
+
+	exifOffset, err := walkIFD(data, order, order.Uint32(data[4:8]), visit)
+	if err != nil {
+		return meta, err
+	}
+	if exifOffset != 0 {
+		if _, err := walkIFD(data, order, exifOffset, visit); err != nil {
+			return meta, err
+		}
+	}
+
+	for _, raw := range []string{dateTimeOriginal, dateTime} {
+		if t, err := time.Parse("2006:01:02 15:04:05", raw); err == nil {
+			meta.TakenAt = &t
+			break
+		}
+	}
+
+	return meta, nil
+}
+
+// walkIFD calls visit for every entry of the IFD at offset and returns the Exif sub-IFD offset if present
+func walkIFD(data []byte, order binary.ByteOrder, offset uint32, visit func(uint16, func() (uint32, string))) (uint32, error) {
+	if int(offset)+2 > len(data) {
+		return 0, errInvalidExif
+	}
+	count := int(order.Uint16(data[offset:]))
+	var exifOffset uint32
+
+	for i := 0; i < count; i++ {
+		start := int(offset) + 2 + i*12
+		if start+12 > len(data) {
+			return 0, errInvalidExif
+		}
+		entry := data[start : start+12]
+		tag := order.Uint16(entry[0:2])
+		kind := order.Uint16(entry[2:4])
+		n := order.Uint32(entry[4:8])
+
+		value := func() (uint32, string) {
+			switch kind {
+			case 3: // SHORT
+				return uint32(order.Uint16(entry[8:10])), ""
+			case 4: // LONG
+				return order.Uint32(entry[8:12]), ""
+			case 2: // ASCII
+				raw := entry[8:12]
+				if n > 4 {
+					at := order.Uint32(entry[8:12])
+					if uint64(at)+uint64(n) > uint64(len(data)) {
+						return 0, ""
+					}
+					raw = data[at : at+n]
+				} else {
+					raw = raw[:n]
+				}
+				return 0, strings.TrimSpace(string(bytes.TrimRight(raw, "\x00")))
+			}
+			return 0, ""
+		}
+
+		if tag == tagExifIFD {
+			exifOffset, _ = value()
+			continue
+		}
+		visit(tag, value)
+	}
+
+	return exifOffset, nil
+}
+
+// extractExif finds the raw EXIF blob in a JPEG, PNG or WebP file
+func extractExif(data []byte, contentType string) []byte {
+	switch contentType {
+	case "image/jpeg":
+		return jpegExif(data)
+	case "image/png":
+		for _, chunk := range pngChunks(data) {
+			if chunk.kind == "eXIf" {
+				return chunk.data
+			}
+		}
+	case "image/webp":
+		for _, chunk := range riffChunks(data) {
+			if chunk.kind == "EXIF" {
+				return bytes.TrimPrefix(chunk.data, []byte("Exif\x00\x00"))
+			}
+		}
+	}
+	return nil
+}
+
+// jpegExif returns the payload of the APP1 Exif segment
+func jpegExif(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return nil
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan or end of image
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+		segment := data[i+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		i = end
+	}
+	return nil
+}
+
+type chunk struct {
+	kind string
+	data []byte
+}
+
+// pngChunks splits a PNG file into its chunks
+func pngChunks(data []byte) []chunk {
+	var chunks []chunk
+	for i := 8; i+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			break
+		}
+		chunks = append(chunks, chunk{kind: string(data[i+4 : i+8]), data: data[i+8 : i+8+length]})
+		i = end
+	}
+	return chunks
+}
+
+// riffChunks splits a WebP (RIFF) file into its top level chunks
+func riffChunks(data []byte) []chunk {
+	var chunks []chunk
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil
+	}
+	for i := 12; i+8 <= len(data); {
+		length := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		end := i + 8 + length
+		if length < 0 || end > len(data) {
+			break
+		}
+		chunks = append(chunks, chunk{kind: string(data[i : i+4]), data: data[i+8 : end]})
+		// chunks are padded to an even size
+		if length%2 == 1 {
+			end++
+		}
+		i = end
+	}
+	return chunks
+}
@@ -0,0 +1,347 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+	"time"
+)
+
+// exifEntry is a SHORT or ASCII entry of a test IFD
+type exifEntry struct {
+	tag   uint16
+	short uint16
+	ascii string
+}
+
+// buildExif writes a TIFF structured EXIF blob with IFD0 and, when exifEntries is not
+// empty, an Exif sub-IFD. ASCII values longer than four bytes go to a data area.
+func buildExif(order binary.ByteOrder, entries, exifEntries []exifEntry) []byte {
+	ifdSize := func(n int) int { return 2 + 12*n + 4 }
+	ifd0Count := len(entries)
+	if len(exifEntries) > 0 {
+		ifd0Count++
+	}
+	exifOffset := 8 + ifdSize(ifd0Count)
+	dataOffset := exifOffset
+	if len(exifEntries) > 0 {
+		dataOffset += ifdSize(len(exifEntries))
+	}
+
+	var data []byte
+	writeIFD := func(buf *bytes.Buffer, entries []exifEntry, subIFD uint32) {
+		count := len(entries)
+		if subIFD != 0 {
+			count++
+		}
+		binary.Write(buf, order, uint16(count))
+		for _, e := range entries {
+			binary.Write(buf, order, e.tag)
+			value := make([]byte, 4)
+			if e.ascii == "" {
+				binary.Write(buf, order, uint16(3))
+				binary.Write(buf, order, uint32(1))
+				order.PutUint16(value, e.short)
+			} else {
+				text := append([]byte(e.ascii), 0)
+				binary.Write(buf, order, uint16(2))
+				binary.Write(buf, order, uint32(len(text)))
+				if len(text) <= 4 {
+					copy(value, text)
+				} else {
+					order.PutUint32(value, uint32(dataOffset+len(data)))
+					data = append(data, text...)
+				}
+			}
+			buf.Write(value)
+		}
+		if subIFD != 0 {
+			binary.Write(buf, order, uint16(tagExifIFD))
+			binary.Write(buf, order, uint16(4))
+			binary.Write(buf, order, uint32(1))
+			binary.Write(buf, order, subIFD)
+		}
+		binary.Write(buf, order, uint32(0)) // no next IFD
+	}
+
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II*\x00")
+	} else {
+		buf.WriteString("MM\x00*")
+	}
+	binary.Write(&buf, order, uint32(8))
+	var subIFD uint32
+	if len(exifEntries) > 0 {
+		subIFD = uint32(exifOffset)
+	}
+	writeIFD(&buf, entries, subIFD)
+	if len(exifEntries) > 0 {
+		writeIFD(&buf, exifEntries, 0)
+	}
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestParseExif(t *testing.T) {
+	raw := buildExif(binary.LittleEndian,
+		[]exifEntry{
+			{tag: tagOrientation, short: 6},
+			{tag: tagMake, ascii: "Canon"},
+			{tag: tagModel, ascii: "R5 "},
+			{tag: tagDateTime, ascii: "2023:01:02 03:04:05"},
+		},
+		[]exifEntry{{tag: tagDateTimeOriginal, ascii: "2022:12:31 23:59:58"}},
+	)
+
+	meta, err := parseExif(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Orientation != 6 || meta.CameraMake != "Canon" || meta.CameraModel != "R5" {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+	want := time.Date(2022, 12, 31, 23, 59, 58, 0, time.UTC)
+	if meta.TakenAt == nil || !meta.TakenAt.Equal(want) {
+		t.Errorf("taken at %v, want the original time %v", meta.TakenAt, want)
+	}
+}
+
+func TestParseExifBigEndian(t *testing.T) {
+	raw := buildExif(binary.BigEndian, []exifEntry{
+		{tag: tagOrientation, short: 3},
+		{tag: tagDateTime, ascii: "2023:01:02 03:04:05"},
+	}, nil)
+
+	meta, err := parseExif(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Orientation != 3 {
+		t.Errorf("orientation %d, want 3", meta.Orientation)
+	}
+	if meta.TakenAt == nil || meta.TakenAt.Year() != 2023 {
+		t.Errorf("taken at %v, want the modification time", meta.TakenAt)
+	}
+}
+
+func TestParseExifIgnoresInvalidValues(t *testing.T) {
+	raw := buildExif(binary.LittleEndian, []exifEntry{
+		{tag: tagOrientation, short: 9},
+		{tag: tagDateTime, ascii: "yesterday"},
+	}, nil)
+	meta, err := parseExif(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Orientation != 1 || meta.TakenAt != nil {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+
+	// A make stored past the end of the blob is skipped
+	outOfRange := buildExif(binary.LittleEndian, []exifEntry{{tag: tagMake, ascii: "Fujifilm"}}, nil)
+	binary.LittleEndian.PutUint32(outOfRange[8+2+8:], 1<<20)
+	meta, err = parseExif(outOfRange)
+	if err != nil || meta.CameraMake != "" {
+		t.Errorf("got %+v, %v; want the make skipped", meta, err)
+	}
+}
+
+func TestParseExifErrors(t *testing.T) {
+	valid := buildExif(binary.LittleEndian, []exifEntry{{tag: tagOrientation, short: 6}, {tag: tagMake, ascii: "Nikon"}}, nil)
+	badOffset := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint32(badOffset[4:], 1<<16)
+
+	tests := map[string][]byte{
+		"empty":            nil,
+		"short":            []byte("II*\x00"),
+		"bad byte order":   append([]byte("XX*\x00"), valid[4:]...),
+		"IFD out of range": badOffset,
+		"truncated IFD":    valid[:8+2+12],
+	}
+	for name, raw := range tests {
+		if _, err := parseExif(raw); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// testJPEG encodes a JPEG of the given size with an APP1 Exif segment holding exif
+func testJPEG(t *testing.T, width, height int, exif []byte) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+
+	segment := append([]byte("Exif\x00\x00"), exif...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte(nil), encoded[:2]...)
+	out = append(out, app1...)
+	return append(out, encoded[2:]...)
+}
+
+func TestSanitizeJPEG(t *testing.T) {
+	raw := buildExif(binary.BigEndian, []exifEntry{{tag: tagOrientation, short: 6}, {tag: tagModel, ascii: "Pixel 8"}}, nil)
+	data := testJPEG(t, 40, 20, raw)
+	if !bytes.Equal(extractExif(data, "image/jpeg"), raw) {
+		t.Fatal("EXIF blob not found in the test JPEG")
+	}
+
+	out, contentType, meta, err := Sanitize(data, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "image/jpeg" || meta.Orientation != 6 || meta.CameraModel != "Pixel 8" {
+		t.Errorf("got %s with %+v", contentType, meta)
+	}
+	if extractExif(out, "image/jpeg") != nil {
+		t.Error("sanitized JPEG still carries EXIF")
+	}
+	width, height, err := Dimensions(out)
+	if err != nil || width != 20 || height != 40 {
+		t.Errorf("got %dx%d (%v), want the image rotated to 20x40", width, height, err)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		want        []color.RGBA // pixels in row order
+		width       int
+	}{
+		{1, []color.RGBA{red, blue}, 2},
+		{2, []color.RGBA{blue, red}, 2},
+		{3, []color.RGBA{blue, red}, 2},
+		{4, []color.RGBA{red, blue}, 2},
+		{5, []color.RGBA{red, blue}, 1},
+		{6, []color.RGBA{red, blue}, 1},
+		{7, []color.RGBA{blue, red}, 1},
+		{8, []color.RGBA{blue, red}, 1},
+	}
+	for _, tt := range tests {
+		dst := Orient(src, tt.orientation)
+		bounds := dst.Bounds()
+		if bounds.Dx() != tt.width {
+			t.Errorf("orientation %d: width %d, want %d", tt.orientation, bounds.Dx(), tt.width)
+			continue
+		}
+		i := 0
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if got := color.RGBAModel.Convert(dst.At(x, y)); got != tt.want[i] {
+					t.Errorf("orientation %d: pixel %d is %v, want %v", tt.orientation, i, got, tt.want[i])
+				}
+				i++
+			}
+		}
+	}
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	chunk := func(kind string, payload []byte) []byte {
+		out := append([]byte(kind), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(out[4:], uint32(len(payload)))
+		out = append(out, payload...)
+		if len(payload)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	riff := func(chunks ...[]byte) []byte {
+		body := []byte("WEBP")
+		for _, c := range chunks {
+			body = append(body, c...)
+		}
+		out := append([]byte("RIFF"), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(out[4:], uint32(len(body)))
+		return append(out, body...)
+	}
+
+	vp8x := []byte{0x08 | 0x04 | 0x10, 0, 0, 0, 1, 0, 0, 1, 0, 0}
+	bitstream := []byte{1, 2, 3}
+	data := riff(chunk("VP8X", vp8x), chunk("VP8L", bitstream), chunk("EXIF", []byte("Exif\x00\x00II*\x00")), chunk("XMP ", []byte("<x/>")))
+
+	got := stripWebPMetadata(data)
+	want := riff(chunk("VP8X", append([]byte{0x10}, vp8x[1:]...)), chunk("VP8L", bitstream))
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if notWebP := []byte("not a webp"); !bytes.Equal(stripWebPMetadata(notWebP), notWebP) {
+		t.Error("expected other data to be returned unchanged")
+	}
+}
+
+func TestStripGIFMetadata(t *testing.T) {
+	frame := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{Image: []*image.Paletted{frame, frame}, Delay: []int{10, 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clean := buf.Bytes()
+
+	comment := []byte{0x21, 0xFE, 5, 'h', 'e', 'l', 'l', 'o', 0}
+	xmp := append([]byte{0x21, 0xFF, 11}, "XMP DataXMP"...)
+	xmp = append(xmp, 3, 'x', 'm', 'p', 0)
+	data := append(append(append([]byte(nil), clean[:13]...), comment...), xmp...)
+	data = append(data, clean[13:]...)
+
+	got, err := stripGIFMetadata(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, clean) {
+		t.Errorf("got %d bytes, want the %d bytes without comment and XMP", len(got), len(clean))
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(got))
+	if err != nil || len(decoded.Image) != 2 || decoded.LoopCount != 0 {
+		t.Errorf("stripped GIF lost its animation: %v", err)
+	}
+
+	for _, bad := range [][]byte{nil, []byte("GIF89a"), append(append([]byte(nil), clean[:13]...), 0x99)} {
+		if _, err := stripGIFMetadata(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestSanitizeRejectsLargeImages(t *testing.T) {
+	frame := image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black})
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, frame, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// Declare a 60000x60000 logical screen
+	binary.LittleEndian.PutUint16(data[6:], 60000)
+	binary.LittleEndian.PutUint16(data[8:], 60000)
+
+	if _, _, _, err := Sanitize(data, "image/gif"); err != ErrImageTooLarge {
+		t.Errorf("got %v, want ErrImageTooLarge", err)
+	}
+
+	t.Setenv("IMAGE_MAX_PIXELS", "100")
+	if err := CheckDimensions(testJPEG(t, 20, 20, nil)); err != ErrImageTooLarge {
+		t.Errorf("got %v, want ErrImageTooLarge over IMAGE_MAX_PIXELS", err)
+	}
+	if err := CheckDimensions(testJPEG(t, 10, 10, nil)); err != nil {
+		t.Errorf("got %v for an image at the limit", err)
+	}
+}
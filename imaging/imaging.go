@@ -0,0 +1,150 @@
+// imaging/imaging.go
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Size describes a named variant that fits within MaxDimension pixels
+type Size struct {
+	Name         string
+	MaxDimension int
+}
+
+// DefaultSizes are used when PHOTO_VARIANTS is not set
+var DefaultSizes = []Size{
+	{Name: "thumbnail", MaxDimension: 150},
+	{Name: "medium", MaxDimension: 640},
+	{Name: "large", MaxDimension: 1080},
+}
+
+// ConfiguredSizes reads PHOTO_VARIANTS, e.g. "thumbnail:150,medium:640,large:1080"
+func ConfiguredSizes() []Size {
+	raw := os.Getenv("PHOTO_VARIANTS")
+	if raw == "" {
+		return DefaultSizes
+	}
+
+	var sizes []Size
+	for _, entry := range strings.Split(raw, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n <= 0 {
+			continue
+		}
+		sizes = append(sizes, Size{Name: parts[0], MaxDimension: n})
+	}
+	if len(sizes) == 0 {
+		return DefaultSizes
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].MaxDimension < sizes[j].MaxDimension })
+	return sizes
+}
+
+const (
+	defaultMaxPixels = 50_000_000
+	maxDimension     = 20_000
+)
+
+// ErrImageTooLarge is returned for images whose pixel size is over the limits, which
+// small compressed files can declare to exhaust memory when decoded
+var ErrImageTooLarge = errors.New("Image dimensions are too large")
+
+// MaxPixels returns the largest width times height accepted, from IMAGE_MAX_PIXELS
+func MaxPixels() int {
+	if n, err := strconv.Atoi(os.Getenv("IMAGE_MAX_PIXELS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultMaxPixels
+}
+
+// CheckDimensions reads the declared size of an encoded image and rejects it before
+// decoding when it is over the limits
+func CheckDimensions(data []byte) error {
+	width, height, err := Dimensions(data)
+	if err != nil {
+		return err
+	}
+	if width <= 0 || height <= 0 || width > maxDimension || height > maxDimension || width*height > MaxPixels() {
+		return ErrImageTooLarge
+	}
+	return nil
+}
+
+// Decode decodes a JPEG, PNG, GIF or WebP image within the size limits
+func Decode(data []byte) (image.Image, string, error) {
+	if err := CheckDimensions(data); err != nil {
+		return nil, "", err
+	}
+	return image.Decode(bytes.NewReader(data))
+}
+
+// Dimensions reads the width and height of an encoded image without decoding it fully
+func Dimensions(data []byte) (int, int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+// Fit scales img down so neither side exceeds maxDimension; smaller images are returned unchanged
+func Fit(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxDimension && height <= maxDimension {
+		return img
+	}
+
+	if width >= height {
+		height = height * maxDimension / width
+		width = maxDimension
+	} else {
+		width = width * maxDimension / height
+		height = maxDimension
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// Encode writes img as JPEG, or as PNG when the source format may carry transparency.
+// There is no pure Go WebP encoder, so WebP sources are re-encoded as PNG as well.
+func Encode(img image.Image, sourceFormat string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch sourceFormat {
+	case "png", "gif", "webp":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	default:
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+}
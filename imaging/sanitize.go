@@ -0,0 +1,223 @@
+// imaging/sanitize.go
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+// Sanitize applies the EXIF orientation to the pixels and returns the image with all
+// metadata removed, its content type and the metadata that was found.
+// JPEG and PNG are re-encoded. WebP has its EXIF and XMP chunks dropped; a rotated
+// WebP is re-encoded as PNG, since there is no WebP encoder. GIF carries no EXIF and
+// has its comment and application extensions dropped, except the animation loop count.
+// Images over the size limits are rejected with ErrImageTooLarge before decoding.
+func Sanitize(data []byte, contentType string) ([]byte, string, Metadata, error) {
+	meta := Metadata{Orientation: 1}
+	if err := CheckDimensions(data); err != nil {
+		return nil, contentType, meta, err
+	}
+	if raw := extractExif(data, contentType); raw != nil {
+		if parsed, err := parseExif(raw); err == nil {
+			meta = parsed
+		}
+	}
+
+	switch contentType {
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, contentType, meta, err
+		}
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, Orient(img, meta.Orientation), &jpeg.Options{Quality: 92}); err != nil {
+			return nil, contentType, meta, err
+		}
+		return buf.Bytes(), contentType, meta, nil
+	case "image/png":
+		data, err := encodeOrientedPNG(data, meta.Orientation)
+		return data, contentType, meta, err
+	case "image/webp":
+		if meta.Orientation > 1 && meta.Orientation <= 8 {
+			// Animated WebP cannot be decoded; it keeps its frames unrotated
+			if oriented, err := encodeOrientedPNG(data, meta.Orientation); err == nil {
+				return oriented, "image/png", meta, nil
+			}
+		}
+		return stripWebPMetadata(data), contentType, meta, nil
+	case "image/gif":
+		data, err := stripGIFMetadata(data)
+		return data, contentType, meta, err
+	default:
+		return data, contentType, meta, nil
+	}
+}
+
+// encodeOrientedPNG decodes an image, applies the orientation and encodes it as PNG
+func encodeOrientedPNG(data []byte, orientation int) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Orient(img, orientation)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Orient rotates and flips img according to an EXIF orientation value (1-8)
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror horizontal
+				sx, sy = w-1-x, y
+			case 3: // rotate 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 270 clockwise
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+// stripWebPMetadata removes the EXIF and XMP chunks of a WebP file
+func stripWebPMetadata(data []byte) []byte {
+	chunks := riffChunks(data)
+	if chunks == nil {
+		return data
+	}
+
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, c := range chunks {
+		if c.kind == "EXIF" || c.kind == "XMP " {
+			continue
+		}
+		payload := c.data
+		if c.kind == "VP8X" && len(payload) > 0 {
+			// clear the EXIF (0x08) and XMP (0x04) presence flags
+			payload = append([]byte(nil), payload...)
+			payload[0] &^= 0x08 | 0x04
+		}
+		body.WriteString(c.kind)
+		binary.Write(&body, binary.LittleEndian, uint32(len(payload)))
+		body.Write(payload)
+		if len(payload)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+// stripGIFMetadata drops the comment, plain text and application extensions of a GIF
+// file, keeping the NETSCAPE2.0 extension that holds the animation loop count
+func stripGIFMetadata(data []byte) ([]byte, error) {
+	errMalformed := errors.New("malformed GIF")
+	if len(data) < 13 || string(data[:3]) != "GIF" {
+		return nil, errMalformed
+	}
+
+	// Header, logical screen descriptor and global color table
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (int(data[10]&0x07) + 1)
+	}
+	if pos > len(data) {
+		return nil, errMalformed
+	}
+	out := bytes.NewBuffer(append([]byte(nil), data[:pos]...))
+
+	// skipSubBlocks returns the position after the data sub-blocks starting at i
+	skipSubBlocks := func(i int) (int, error) {
+		for i < len(data) {
+			size := int(data[i])
+			i++
+			if size == 0 {
+				return i, nil
+			}
+			i += size
+		}
+		return 0, errMalformed
+	}
+
+	for pos < len(data) {
+		start := pos
+		switch data[pos] {
+		case 0x3B: // trailer
+			out.WriteByte(0x3B)
+			return out.Bytes(), nil
+		case 0x2C: // image descriptor, local color table and image data
+			if pos+10 > len(data) {
+				return nil, errMalformed
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (int(flags&0x07) + 1)
+			}
+			end, err := skipSubBlocks(pos + 1) // after the LZW minimum code size
+			if err != nil {
+				return nil, err
+			}
+			out.Write(data[start:end])
+			pos = end
+		case 0x21: // extension
+			if pos+2 > len(data) {
+				return nil, errMalformed
+			}
+			label := data[pos+1]
+			end, err := skipSubBlocks(pos + 2)
+			if err != nil {
+				return nil, err
+			}
+			keep := label == 0xF9 // graphic control: frame delays and transparency
+			if label == 0xFF && end-start > 14 && string(data[start+3:start+14]) == "NETSCAPE2.0" {
+				keep = true
+			}
+			if keep {
+				out.Write(data[start:end])
+			}
+			pos = end
+		default:
+			return nil, errMalformed
+		}
+	}
+
+	// Files without a trailer are accepted by decoders; add one
+	out.WriteByte(0x3B)
+	return out.Bytes(), nil
+}
@@ -0,0 +1,161 @@
+// imaging/worker.go
+package imaging
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/storage"
+)
+
+// Variant generation states stored on models.Photo
+const (
+	VariantsPending = "pending"
+	VariantsReady   = "ready"
+	VariantsFailed  = "failed"
+)
+
+const (
+	pollInterval = 3 * time.Second
+	batchSize    = 5
+)
+
+// StartWorker generates the configured variants for newly uploaded photos until the process exits
+func StartWorker() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		processPending()
+	}
+}
+
+func processPending() {
+	var photos []models.Photo
+	err := database.GetDB().Where("variants_status = ?", VariantsPending).Order("id").Limit(batchSize).Find(&photos).Error
+	if err != nil {
+		log.Printf("imaging: failed to load pending photos: %v", err)
+		return
+	}
+
+	for _, photo := range photos {
+		status := VariantsReady
+		if err := GenerateVariants(photo); err != nil {
+			log.Printf("imaging: failed to generate variants for photo %d: %v", photo.ID, err)
+			status = VariantsFailed
+		}
+		database.GetDB().Model(&photo).UpdateColumn("variants_status", status)
+	}
+}
+
+// GenerateVariants renders every configured size of each stored image of the
+// photo and replaces any variants recorded before
+func GenerateVariants(photo models.Photo) error {
+	var media []models.PhotoMedia
+	err := database.GetDB().Where("photo_id = ?", photo.ID).Order("position, id").Find(&media).Error
+	if err != nil {
+		return err
+	}
+	sources := variantSources(photo, media)
+	if len(sources) == 0 {
+		return fmt.Errorf("photo has no stored image")
+	}
+
+	if err := DeleteVariants(photo.ID); err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if err := generateSourceVariants(photo.ID, source); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// variantSources returns the storage keys of the images to render variants of:
+// every stored media item, or the photo's own image for photos without media items
+func variantSources(photo models.Photo, media []models.PhotoMedia) []string {
+	var sources []string
+	for _, item := range media {
+		if item.StorageKey != "" {
+			sources = append(sources, item.StorageKey)
+		}
+	}
+	if len(media) == 0 && photo.StorageKey != "" {
+		sources = append(sources, photo.StorageKey)
+	}
+	return sources
+}
+
+// generateSourceVariants renders and stores every configured size of one image.
+// Variants are encoded as JPEG or PNG only, see Encode.
+func generateSourceVariants(photoID uint, source string) error {
+	store := storage.GetStorage()
+	data, err := store.Get(source)
+	if err != nil {
+		return err
+	}
+	img, format, err := Decode(data)
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(source, path.Ext(source))
+	for _, size := range ConfiguredSizes() {
+		resized := Fit(img, size.MaxDimension)
+		encoded, contentType, err := Encode(resized, format)
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("%s_%s%s", base, size.Name, extensionFor(contentType))
+		if err := store.Put(key, encoded, contentType); err != nil {
+			return err
+		}
+
+		bounds := resized.Bounds()
+		variant := models.PhotoVariant{
+			PhotoID:     photoID,
+			SourceKey:   source,
+			Name:        size.Name,
+			ContentType: contentType,
+			StorageKey:  key,
+			URL:         store.URL(key),
+			Width:       bounds.Dx(),
+			Height:      bounds.Dy(),
+			Bytes:       len(encoded),
+		}
+		if err := database.GetDB().Create(&variant).Error; err != nil {
+			store.Delete(key)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteVariants removes the stored files and records of a photo's variants
+func DeleteVariants(photoID uint) error {
+	var variants []models.PhotoVariant
+	if err := database.GetDB().Where("photo_id = ?", photoID).Find(&variants).Error; err != nil {
+		return err
+	}
+	for _, variant := range variants {
+		if err := storage.GetStorage().Delete(variant.StorageKey); err != nil {
+			log.Printf("imaging: failed to delete variant %s: %v", variant.StorageKey, err)
+		}
+	}
+	return database.GetDB().Where("photo_id = ?", photoID).Delete(models.PhotoVariant{}).Error
+}
+
+func extensionFor(contentType string) string {
+	if contentType == "image/png" {
+		return ".png"
+	}
+	return ".jpg"
+}
@@ -0,0 +1,47 @@
+package imaging
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/faazabilamri7/mygram/models"
+)
+
+func TestVariantSources(t *testing.T) {
+	tests := []struct {
+		name  string
+		photo models.Photo
+		media []models.PhotoMedia
+		want  []string
+	}{
+		{
+			name:  "every stored media item",
+			photo: models.Photo{StorageKey: "photos/1/a.jpg"},
+			media: []models.PhotoMedia{
+				{StorageKey: "photos/1/a.jpg"},
+				{URL: "https://img.example/b.jpg"},
+				{StorageKey: "photos/1/c.png"},
+			},
+			want: []string{"photos/1/a.jpg", "photos/1/c.png"},
+		},
+		{
+			name:  "photo without media items",
+			photo: models.Photo{StorageKey: "photos/1/a.jpg"},
+			want:  []string{"photos/1/a.jpg"},
+		},
+		{
+			name:  "nothing stored",
+			photo: models.Photo{URL: "https://img.example/a.jpg"},
+			media: []models.PhotoMedia{{URL: "https://img.example/a.jpg"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := variantSources(tt.photo, tt.media)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("variantSources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
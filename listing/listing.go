@@ -0,0 +1,257 @@
+// listing/listing.go
+package listing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Kind tells how a parameter or cursor value is parsed
+type Kind int
+
+const (
+	Int Kind = iota
+	Time
+	String
+)
+
+// Sort is a field a listing can be ordered by
+type Sort struct {
+	Name   string // value of the sort query parameter
+	Column string // qualified SQL column
+	Field  string // Go struct field holding the value
+	Kind   Kind
+}
+
+// Filter maps a query parameter to a condition on a column
+type Filter struct {
+	Param    string // query parameter name
+	Column   string // qualified SQL column
+	Operator string // "=", ">" or "<"
+	Kind     Kind
+}
+
+// Spec describes what a list endpoint supports
+type Spec struct {
+	IDColumn    string // qualified primary key column, used as tie breaker
+	Sorts       []Sort
+	DefaultSort string // e.g. "-created_at" for newest first
+	Filters     []Filter
+}
+
+// Query is a parsed list request
+type Query struct {
+	spec       Spec
+	sort       Sort
+	desc       bool
+	limit      int
+	cursor     *cursor
+	conditions []condition
+}
+
+// Page is the JSON body returned by list endpoints
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type condition struct {
+	sql   string
+	value interface{}
+}
+
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// ErrInvalidCursor is returned for cursors that cannot be decoded or belong to another sort order
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// Parse reads limit, sort, cursor and the filters of spec from the query string
+func Parse(values url.Values, spec Spec) (*Query, error) {
+	q := &Query{spec: spec}
+
+	limit, err := ParseLimit(values)
+	if err != nil {
+		return nil, err
+	}
+	q.limit = limit
+
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+	q.desc = strings.HasPrefix(sortParam, "-")
+	name := strings.TrimPrefix(sortParam, "-")
+	found := false
+	for _, sort := range spec.Sorts {
+		if sort.Name == name {
+			q.sort = sort
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("Invalid sort field %q", name)
+	}
+
+	for _, filter := range spec.Filters {
+		raw := values.Get(filter.Param)
+		if raw == "" {
+			continue
+		}
+		value, err := parseValue(raw, filter.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value for %s", filter.Param)
+		}
+		q.conditions = append(q.conditions, condition{sql: filter.Column + " " + filter.Operator + " ?", value: value})
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		var c cursor
+		if err := json.Unmarshal(decoded, &c); err != nil || c.Sort != sortParam {
+			return nil, ErrInvalidCursor
+		}
+		if _, err := parseValue(c.Value, q.sort.Kind); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		q.cursor = &c
+	}
+
+	return q, nil
+}
+
+// Scope applies the filters, the cursor position, the order and the limit to a query.
+// One row more than the limit is fetched to detect whether another page exists.
+func (q *Query) Scope(db *gorm.DB) *gorm.DB {
+	for _, c := range q.conditions {
+		db = db.Where(c.sql, c.value)
+	}
+
+	direction, operator := "ASC", ">"
+	if q.desc {
+		direction, operator = "DESC", "<"
+	}
+
+	if q.cursor != nil {
+		value, _ := parseValue(q.cursor.Value, q.sort.Kind)
+		db = db.Where(
+			fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", q.sort.Column, operator, q.sort.Column, q.spec.IDColumn, operator),
+			value, value, q.cursor.ID,
+		)
+	}
+
+	return db.Order(q.sort.Column + " " + direction).Order(q.spec.IDColumn + " " + direction).Limit(q.limit + 1)
+}
+
+// Paginate trims the extra row fetched by Scope and builds the cursor of the next page
+func Paginate[T any](q *Query, rows []T) Page[T] {
+	if rows == nil {
+		rows = []T{}
+	}
+	if len(rows) <= q.limit {
+		return Page[T]{Data: rows}
+	}
+
+	rows = rows[:q.limit]
+	last := reflect.Indirect(reflect.ValueOf(rows[len(rows)-1]))
+
+	sortParam := q.sort.Name
+	if q.desc {
+		sortParam = "-" + sortParam
+	}
+	c := cursor{
+		Sort:  sortParam,
+		Value: formatValue(last.FieldByName(q.sort.Field).Interface()),
+		ID:    uint(last.FieldByName("ID").Uint()),
+	}
+	encoded, _ := json.Marshal(c)
+
+	return Page[T]{Data: rows, NextCursor: base64.RawURLEncoding.EncodeToString(encoded)}
+}
+
+func parseValue(raw string, kind Kind) (interface{}, error) {
+	switch kind {
+	case Int:
+		return strconv.ParseInt(raw, 10, 64)
+	case Time:
+		if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02", raw)
+	default:
+		return raw, nil
+	}
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ParseLimit reads the limit query parameter, defaulting to DefaultLimit and capped at MaxLimit
+func ParseLimit(values url.Values) (int, error) {
+	raw := values.Get("limit")
+	if raw == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		return 0, errors.New("Invalid limit")
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return limit, nil
+}
+
+// OffsetCursor encodes an offset for listings that are ranked rather than sorted by a column
+func OffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// ParseOffsetCursor decodes the cursor query parameter written by OffsetCursor
+func ParseOffsetCursor(values url.Values) (int, error) {
+	raw := values.Get("cursor")
+	if raw == "" {
+		return 0, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(string(decoded))
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return offset, nil
+}
@@ -0,0 +1,140 @@
+package listing
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testRow struct {
+	ID        uint
+	Likes     int
+	CreatedAt time.Time
+}
+
+var testSpec = Spec{
+	IDColumn: "rows.id",
+	Sorts: []Sort{
+		{Name: "created_at", Column: "rows.created_at", Field: "CreatedAt", Kind: Time},
+		{Name: "likes", Column: "rows.likes", Field: "Likes", Kind: Int},
+	},
+	DefaultSort: "-created_at",
+}
+
+func mustParse(t *testing.T, query string) *Query {
+	t.Helper()
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := Parse(values, testSpec)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", query, err)
+	}
+	return q
+}
+
+func TestPaginateCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC)
+	rows := []testRow{
+		{ID: 9, CreatedAt: created.Add(2 * time.Hour)},
+		{ID: 7, CreatedAt: created},
+		{ID: 3, CreatedAt: created.Add(-time.Hour)},
+	}
+
+	page := Paginate(mustParse(t, "limit=2"), rows)
+	if len(page.Data) != 2 {
+		t.Fatalf("got %d rows, want 2", len(page.Data))
+	}
+	if page.NextCursor == "" {
+		t.Fatal("expected a next cursor")
+	}
+
+	next := mustParse(t, "limit=2&cursor="+page.NextCursor)
+	if next.cursor == nil {
+		t.Fatal("cursor was not decoded")
+	}
+	if next.cursor.ID != 7 || next.cursor.Sort != "-created_at" {
+		t.Errorf("got cursor %+v, want id 7 on -created_at", *next.cursor)
+	}
+	value, err := parseValue(next.cursor.Value, Time)
+	if err != nil || !value.(time.Time).Equal(created) {
+		t.Errorf("got cursor value %v (%v), want %v", value, err, created)
+	}
+}
+
+func TestPaginateLastPage(t *testing.T) {
+	page := Paginate(mustParse(t, "limit=2"), []testRow{{ID: 1}, {ID: 2}})
+	if len(page.Data) != 2 || page.NextCursor != "" {
+		t.Errorf("got %d rows and cursor %q, want 2 rows and no cursor", len(page.Data), page.NextCursor)
+	}
+
+	empty := Paginate[testRow](mustParse(t, ""), nil)
+	if empty.Data == nil {
+		t.Error("empty page should encode as an empty list")
+	}
+}
+
+func TestParseRejectsInvalidCursor(t *testing.T) {
+	likes := Paginate(mustParse(t, "sort=likes&limit=1"), []testRow{{ID: 1, Likes: 5}, {ID: 2, Likes: 4}})
+	if likes.NextCursor == "" {
+		t.Fatal("expected a next cursor")
+	}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"not base64", "cursor=%21%21"},
+		{"not json", "cursor=" + base64.RawURLEncoding.EncodeToString([]byte("nope"))},
+		{"other sort order", "sort=-likes&cursor=" + likes.NextCursor},
+		{"other sort field", "cursor=" + likes.NextCursor},
+		{"bad value", "sort=likes&cursor=" + base64.RawURLEncoding.EncodeToString([]byte(`{"s":"likes","v":"x","id":1}`))},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		if _, err := Parse(values, testSpec); err != ErrInvalidCursor {
+			t.Errorf("%s: got %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{"", DefaultLimit, false},
+		{"5", 5, false},
+		{"1000", MaxLimit, false},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"ten", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(url.Values{"limit": {tt.raw}})
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %d, %v; want %d, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestOffsetCursor(t *testing.T) {
+	for _, offset := range []int{0, 1, 40, 12345} {
+		got, err := ParseOffsetCursor(url.Values{"cursor": {OffsetCursor(offset)}})
+		if err != nil || got != offset {
+			t.Errorf("round trip of %d gave %d, %v", offset, got, err)
+		}
+	}
+
+	if got, err := ParseOffsetCursor(url.Values{}); err != nil || got != 0 {
+		t.Errorf("missing cursor gave %d, %v; want 0", got, err)
+	}
+	for _, raw := range []string{"!!", base64.RawURLEncoding.EncodeToString([]byte("-5")), base64.RawURLEncoding.EncodeToString([]byte("x"))} {
+		if _, err := ParseOffsetCursor(url.Values{"cursor": {raw}}); err != ErrInvalidCursor {
+			t.Errorf("cursor %q gave %v, want ErrInvalidCursor", raw, err)
+		}
+	}
+}
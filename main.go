@@ -5,9 +5,21 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 
+	"github.com/faazabilamri7/mygram/contentfilter"
 	"github.com/faazabilamri7/mygram/database"
 	"github.com/faazabilamri7/mygram/handlers"
+	"github.com/faazabilamri7/mygram/idempotency"
+	"github.com/faazabilamri7/mygram/imaging"
+	"github.com/faazabilamri7/mygram/publishing"
+	"github.com/faazabilamri7/mygram/ratelimit"
+	"github.com/faazabilamri7/mygram/search"
+	"github.com/faazabilamri7/mygram/storage"
+	"github.com/faazabilamri7/mygram/stories"
+	"github.com/faazabilamri7/mygram/trash"
+	"github.com/faazabilamri7/mygram/trending"
+	"github.com/faazabilamri7/mygram/webhooks"
 	"github.com/gorilla/mux"
 )
 
@@ -24,28 +36,103 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Initialize photo storage backend, search index, content filter and rate limiter
+	storage.InitStorage()
+	search.InitSearch()
+	contentfilter.InitFilter()
+	ratelimit.InitLimiter()
+
+	// Start background workers
+	go webhooks.StartWorker()
+	go imaging.StartWorker()
+	go trash.StartPurger()
+	go trending.StartWorker()
+	go stories.StartArchiver()
+	go publishing.StartScheduler()
+	go idempotency.StartPurger()
+
 	r := mux.NewRouter()
 
+	// Rate limit every endpoint per route group and replay retried POST requests
+	r.Use(handlers.RateLimit)
+	r.Use(handlers.Idempotency)
+
 	//Welcome
 	r.HandleFunc("/", welcomeMessage).Methods("GET")
 
+	// Serve uploaded files when using local storage, checking the viewer may see them
+	if local, ok := storage.GetStorage().(*storage.LocalStorage); ok && strings.HasPrefix(local.PublicURL, "/") {
+		r.PathPrefix(local.PublicURL + "/").Handler(handlers.ServeUpload(local.PublicURL)).Methods("GET")
+	}
+
 	// Register API endpoints
 	r.HandleFunc("/users/register", handlers.RegisterUser).Methods("POST")
 	r.HandleFunc("/users/login", handlers.LoginUser).Methods("POST")
 	r.HandleFunc("/users", handlers.UpdateUser).Methods("PUT")
 	r.HandleFunc("/users", handlers.DeleteUser).Methods("DELETE")
 
+	r.HandleFunc("/users/me/bookmarks", handlers.GetBookmarks).Methods("GET")
+	r.HandleFunc("/users/me/hidden-words", handlers.GetHiddenWords).Methods("GET")
+	r.HandleFunc("/users/me/hidden-words", handlers.AddHiddenWord).Methods("POST")
+	r.HandleFunc("/users/me/hidden-words/{wordID}", handlers.DeleteHiddenWord).Methods("DELETE")
+	r.HandleFunc("/bookmark-collections", handlers.CreateBookmarkCollection).Methods("POST")
+	r.HandleFunc("/bookmark-collections", handlers.GetBookmarkCollections).Methods("GET")
+	r.HandleFunc("/bookmark-collections/{collectionID}", handlers.UpdateBookmarkCollectionByID).Methods("PUT")
+	r.HandleFunc("/bookmark-collections/{collectionID}", handlers.DeleteBookmarkCollectionByID).Methods("DELETE")
+
+	r.HandleFunc("/users/{userID}/follow", handlers.FollowUser).Methods("POST")
+	r.HandleFunc("/users/{userID}/follow", handlers.UnfollowUser).Methods("DELETE")
+	r.HandleFunc("/users/{userID}/followers", handlers.GetFollowers).Methods("GET")
+	r.HandleFunc("/users/{userID}/following", handlers.GetFollowing).Methods("GET")
+	r.HandleFunc("/users/{userID}/follower", handlers.RemoveFollower).Methods("DELETE")
+	r.HandleFunc("/users/{userID}/block", handlers.BlockUser).Methods("POST")
+	r.HandleFunc("/users/{userID}/block", handlers.UnblockUser).Methods("DELETE")
+	r.HandleFunc("/users/{userID}/mute", handlers.MuteUser).Methods("POST")
+	r.HandleFunc("/users/{userID}/mute", handlers.UnmuteUser).Methods("DELETE")
+	r.HandleFunc("/blocks", handlers.GetBlocks).Methods("GET")
+	r.HandleFunc("/mutes", handlers.GetMutes).Methods("GET")
+	r.HandleFunc("/follow-requests", handlers.GetFollowRequests).Methods("GET")
+	r.HandleFunc("/follow-requests/{requestID}/approve", handlers.ApproveFollowRequest).Methods("POST")
+	r.HandleFunc("/follow-requests/{requestID}/deny", handlers.DenyFollowRequest).Methods("POST")
+
 	r.HandleFunc("/photos", handlers.CreatePhoto).Methods("POST")
 	r.HandleFunc("/photos", handlers.GetAllPhotos).Methods("GET")
+	r.HandleFunc("/photos/drafts", handlers.GetDrafts).Methods("GET")
 	r.HandleFunc("/photos/{photoID}", handlers.GetPhotoByID).Methods("GET")
 	r.HandleFunc("/photos/{photoID}", handlers.UpdatePhotoByID).Methods("PUT")
 	r.HandleFunc("/photos/{photoID}", handlers.DeletePhotoByID).Methods("DELETE")
+	r.HandleFunc("/photos/{photoID}/restore", handlers.RestorePhotoByID).Methods("POST")
+	r.HandleFunc("/photos/{photoID}/comment-settings", handlers.UpdateCommentSettings).Methods("PUT")
+	r.HandleFunc("/photos/{photoID}/revisions", handlers.GetPhotoRevisions).Methods("GET")
+	r.HandleFunc("/photos/{photoID}/publish", handlers.PublishPhoto).Methods("POST")
+	r.HandleFunc("/photos/{photoID}/schedule", handlers.CancelScheduledPhoto).Methods("DELETE")
+	r.HandleFunc("/photos/{photoID}/media/order", handlers.ReorderPhotoMedia).Methods("PUT")
+	r.HandleFunc("/photos/{photoID}/media/{mediaID}", handlers.ReplacePhotoMedia).Methods("PUT")
+	r.HandleFunc("/photos/{photoID}/like", handlers.LikePhoto).Methods("POST")
+	r.HandleFunc("/photos/{photoID}/like", handlers.UnlikePhoto).Methods("DELETE")
+	r.HandleFunc("/photos/{photoID}/bookmark", handlers.BookmarkPhoto).Methods("POST")
+	r.HandleFunc("/photos/{photoID}/bookmark", handlers.UnbookmarkPhoto).Methods("DELETE")
+
+	r.HandleFunc("/stories", handlers.CreateStory).Methods("POST")
+	r.HandleFunc("/stories/tray", handlers.GetStoryTray).Methods("GET")
+	r.HandleFunc("/stories/archive", handlers.GetStoryArchive).Methods("GET")
+	r.HandleFunc("/stories/{storyID}", handlers.GetStoryByID).Methods("GET")
+	r.HandleFunc("/stories/{storyID}", handlers.DeleteStoryByID).Methods("DELETE")
+	r.HandleFunc("/stories/{storyID}/views", handlers.GetStoryViews).Methods("GET")
 
 	r.HandleFunc("/comments", handlers.CreateComment).Methods("POST")
 	r.HandleFunc("/comments", handlers.GetAllComments).Methods("GET")
 	r.HandleFunc("/comments/{commentID}", handlers.GetCommentByID).Methods("GET")
 	r.HandleFunc("/comments/{commentID}", handlers.UpdateCommentByID).Methods("PUT")
 	r.HandleFunc("/comments/{commentID}", handlers.DeleteCommentByID).Methods("DELETE")
+	r.HandleFunc("/comments/{commentID}/restore", handlers.RestoreCommentByID).Methods("POST")
+	r.HandleFunc("/comments/{commentID}/pin", handlers.PinComment).Methods("POST")
+	r.HandleFunc("/comments/{commentID}/pin", handlers.UnpinComment).Methods("DELETE")
+	r.HandleFunc("/comments/{commentID}/reactions", handlers.AddCommentReaction).Methods("POST")
+	r.HandleFunc("/comments/{commentID}/reactions/{emoji}", handlers.RemoveCommentReaction).Methods("DELETE")
+	r.HandleFunc("/comments/{commentID}/revisions", handlers.GetCommentRevisions).Methods("GET")
+
+	r.HandleFunc("/trash", handlers.GetTrash).Methods("GET")
 
 	r.HandleFunc("/socialmedias", handlers.CreateSocialMediaEntry).Methods("POST")
 	r.HandleFunc("/socialmedias", handlers.GetAllSocialMediaEntries).Methods("GET")
@@ -53,6 +140,45 @@ func main() {
 	r.HandleFunc("/socialmedias/{socialMediaID}", handlers.UpdateSocialMediaEntryByID).Methods("PUT")
 	r.HandleFunc("/socialmedias/{socialMediaID}", handlers.DeleteSocialMediaEntryByID).Methods("DELETE")
 
+	r.HandleFunc("/conversations", handlers.CreateConversation).Methods("POST")
+	r.HandleFunc("/conversations", handlers.GetConversations).Methods("GET")
+	r.HandleFunc("/conversations/{conversationID}", handlers.GetConversationByID).Methods("GET")
+	r.HandleFunc("/conversations/{conversationID}/messages", handlers.GetMessages).Methods("GET")
+	r.HandleFunc("/conversations/{conversationID}/messages", handlers.SendMessage).Methods("POST")
+	r.HandleFunc("/conversations/{conversationID}/read", handlers.MarkConversationRead).Methods("POST")
+	r.HandleFunc("/messages/stream", handlers.StreamMessages).Methods("GET")
+
+	r.HandleFunc("/search", handlers.Search).Methods("GET")
+	r.HandleFunc("/explore", handlers.GetExplore).Methods("GET")
+	r.HandleFunc("/tags/trending", handlers.GetTrendingTags).Methods("GET")
+
+	r.HandleFunc("/albums", handlers.CreateAlbum).Methods("POST")
+	r.HandleFunc("/albums", handlers.GetAllAlbums).Methods("GET")
+	r.HandleFunc("/albums/{albumID}", handlers.GetAlbumByID).Methods("GET")
+	r.HandleFunc("/albums/{albumID}", handlers.UpdateAlbumByID).Methods("PUT")
+	r.HandleFunc("/albums/{albumID}", handlers.DeleteAlbumByID).Methods("DELETE")
+	r.HandleFunc("/albums/{albumID}/photos", handlers.AddAlbumPhoto).Methods("POST")
+	r.HandleFunc("/albums/{albumID}/photos/order", handlers.ReorderAlbumPhotos).Methods("PUT")
+	r.HandleFunc("/albums/{albumID}/photos/{photoID}", handlers.RemoveAlbumPhoto).Methods("DELETE")
+
+	r.HandleFunc("/reports", handlers.CreateReport).Methods("POST")
+	r.HandleFunc("/moderation/reports", handlers.GetModerationReports).Methods("GET")
+	r.HandleFunc("/moderation/reports/{reportID}", handlers.GetModerationReportByID).Methods("GET")
+	r.HandleFunc("/moderation/reports/{reportID}", handlers.UpdateModerationReport).Methods("PUT")
+	r.HandleFunc("/moderation/reports/{reportID}/actions", handlers.ApplyModerationAction).Methods("POST")
+	r.HandleFunc("/moderation/actions", handlers.GetModerationActions).Methods("GET")
+	r.HandleFunc("/moderation/spam-scores", handlers.GetSpamScores).Methods("GET")
+	r.HandleFunc("/moderation/users/{userID}/verification", handlers.UpdateUserVerification).Methods("PUT")
+
+	r.HandleFunc("/webhooks", handlers.CreateWebhook).Methods("POST")
+	r.HandleFunc("/webhooks", handlers.GetAllWebhooks).Methods("GET")
+	r.HandleFunc("/webhooks/{webhookID}", handlers.GetWebhookByID).Methods("GET")
+	r.HandleFunc("/webhooks/{webhookID}", handlers.UpdateWebhookByID).Methods("PUT")
+	r.HandleFunc("/webhooks/{webhookID}", handlers.DeleteWebhookByID).Methods("DELETE")
+	r.HandleFunc("/webhooks/{webhookID}/deliveries", handlers.GetWebhookDeliveries).Methods("GET")
+	r.HandleFunc("/webhooks/{webhookID}/deliveries/{deliveryID}/attempts", handlers.GetWebhookDeliveryAttempts).Methods("GET")
+	r.HandleFunc("/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", handlers.RedeliverWebhookDelivery).Methods("POST")
+
 	// Start server
 	// Start server
 	port := os.Getenv("PORT")
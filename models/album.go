@@ -0,0 +1,35 @@
+// models/album.go
+package models
+
+import (
+	"time"
+)
+
+// Album visibility levels
+const (
+	AlbumPublic  = "public"
+	AlbumPrivate = "private"
+)
+
+type Album struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `json:"user_id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	CoverPhotoID *uint     `json:"cover_photo_id"`
+	Visibility   string    `json:"visibility"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	User         User      `gorm:"foreignKey:UserID" json:"-"`
+	Photos       []Photo   `gorm:"-" json:"photos,omitempty"`
+}
+
+type AlbumPhoto struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AlbumID   uint      `gorm:"unique_index:idx_album_photo" json:"album_id"`
+	PhotoID   uint      `gorm:"unique_index:idx_album_photo" json:"photo_id"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	Album     Album     `gorm:"foreignKey:AlbumID" json:"-"`
+	Photo     Photo     `gorm:"foreignKey:PhotoID" json:"-"`
+}
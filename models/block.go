@@ -0,0 +1,24 @@
+// models/block.go
+package models
+
+import (
+	"time"
+)
+
+type Block struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BlockerID uint      `gorm:"unique_index:idx_block" json:"blocker_id"`
+	BlockedID uint      `gorm:"unique_index:idx_block" json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Blocker   User      `gorm:"foreignKey:BlockerID" json:"-"`
+	Blocked   User      `gorm:"foreignKey:BlockedID" json:"-"`
+}
+
+type Mute struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MuterID   uint      `gorm:"unique_index:idx_mute" json:"muter_id"`
+	MutedID   uint      `gorm:"unique_index:idx_mute" json:"muted_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Muter     User      `gorm:"foreignKey:MuterID" json:"-"`
+	Muted     User      `gorm:"foreignKey:MutedID" json:"-"`
+}
@@ -0,0 +1,27 @@
+// models/bookmark.go
+package models
+
+import (
+	"time"
+)
+
+// Bookmark is a photo a user saved privately, optionally into a named collection
+type Bookmark struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"unique_index:idx_bookmark" json:"user_id"`
+	PhotoID      uint      `gorm:"unique_index:idx_bookmark;index" json:"photo_id"`
+	CollectionID *uint     `gorm:"index" json:"collection_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	User         User      `gorm:"foreignKey:UserID" json:"-"`
+	Photo        Photo     `gorm:"foreignKey:PhotoID" json:"photo"`
+}
+
+// BookmarkCollection is a named group of a user's bookmarks
+type BookmarkCollection struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"unique_index:idx_bookmark_collection" json:"user_id"`
+	Name      string    `gorm:"unique_index:idx_bookmark_collection" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -0,0 +1,23 @@
+// models/follow.go
+package models
+
+import (
+	"time"
+)
+
+// Follow states
+const (
+	FollowPending  = "pending"
+	FollowAccepted = "accepted"
+)
+
+type Follow struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	FollowerID uint      `gorm:"unique_index:idx_follow" json:"follower_id"`
+	FolloweeID uint      `gorm:"unique_index:idx_follow" json:"followee_id"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Follower   User      `gorm:"foreignKey:FollowerID" json:"-"`
+	Followee   User      `gorm:"foreignKey:FolloweeID" json:"-"`
+}
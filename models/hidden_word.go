@@ -0,0 +1,15 @@
+// models/hidden_word.go
+package models
+
+import (
+	"time"
+)
+
+// HiddenWord is a word a user does not want to see in comments on their photos.
+// Comments containing it are hidden from everyone but their author.
+type HiddenWord struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"unique_index:idx_hidden_word" json:"user_id"`
+	Word      string    `gorm:"size:100;unique_index:idx_hidden_word" json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+// models/idempotency.go
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey is the first response to a request sent with an Idempotency-Key
+// header, replayed when the request is retried with the same key
+type IdempotencyKey struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"unique_index:idx_idempotency_key" json:"user_id"`
+	Key         string    `gorm:"column:idempotency_key;size:255;unique_index:idx_idempotency_key" json:"key"`
+	RequestHash string    `json:"request_hash"`
+	StatusCode  int       `json:"status_code"` // 0 while the first request is in progress
+	ContentType string    `json:"content_type"`
+	Location    string    `gorm:"size:2048" json:"location"`
+	RetryAfter  string    `json:"retry_after"`
+	Body        string    `gorm:"type:mediumtext" json:"body"`
+	ExpiresAt   time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+// models/like.go
+package models
+
+import (
+	"time"
+)
+
+// Like records a user liking a photo
+type Like struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"unique_index:idx_like" json:"user_id"`
+	PhotoID   uint      `gorm:"unique_index:idx_like;index" json:"photo_id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	Photo     Photo     `gorm:"foreignKey:PhotoID" json:"-"`
+}
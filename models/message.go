@@ -0,0 +1,44 @@
+// models/message.go
+package models
+
+import (
+	"time"
+)
+
+// MaxConversationSize is the largest number of participants a group conversation can have
+const MaxConversationSize = 10
+
+// Conversation is a private message thread between two users or a small group
+type Conversation struct {
+	ID           uint                      `gorm:"primaryKey" json:"id"`
+	IsGroup      bool                      `json:"is_group"`
+	Title        string                    `json:"title,omitempty"`
+	CreatedByID  uint                      `json:"created_by_id"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+	Participants []ConversationParticipant `gorm:"foreignKey:ConversationID" json:"participants"`
+	LastMessage  *Message                  `gorm:"-" json:"last_message,omitempty"`
+	UnreadCount  int                       `gorm:"-" json:"unread_count"`
+}
+
+// ConversationParticipant is a member of a conversation and how far they have read
+type ConversationParticipant struct {
+	ID                uint       `gorm:"primaryKey" json:"-"`
+	ConversationID    uint       `gorm:"unique_index:idx_conversation_participant" json:"-"`
+	UserID            uint       `gorm:"unique_index:idx_conversation_participant;index" json:"user_id"`
+	LastReadMessageID uint       `json:"last_read_message_id"`
+	LastReadAt        *time.Time `json:"last_read_at"`
+	CreatedAt         time.Time  `json:"joined_at"`
+}
+
+// Message is a text message in a conversation, optionally sharing a photo
+type Message struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ConversationID uint      `gorm:"index" json:"conversation_id"`
+	SenderID       uint      `json:"sender_id"`
+	Body           string    `json:"body"`
+	PhotoID        *uint     `json:"photo_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Photo          *Photo    `gorm:"-" json:"photo,omitempty"`
+	ReadBy         []uint    `gorm:"-" json:"read_by"`
+}
@@ -7,6 +7,27 @@ import (
 	_ "gorm.io/gorm"
 )
 
+// Photo visibility levels
+const (
+	PhotoPublic    = "public"
+	PhotoFollowers = "followers"
+	PhotoPrivate   = "private"
+)
+
+// Who may comment on a photo
+const (
+	CommentsEveryone  = "everyone"
+	CommentsFollowers = "followers"
+	CommentsOff       = "off"
+)
+
+// Photo publishing states. Drafts and scheduled photos are only visible to their owner.
+const (
+	PhotoDraft     = "draft"
+	PhotoScheduled = "scheduled"
+	PhotoPublished = "published"
+)
+
 type SocialMedia struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	Name      string    `json:"name"`
@@ -18,37 +39,69 @@ type SocialMedia struct {
 }
 
 type User struct {
-	ID           uint          `gorm:"primaryKey" json:"id"`
-	Username     string        `json:"username"`
-	Email        string        `json:"email"`
-	Password     string        `json:"password"`
-	Age          int           `json:"age"`
-	ImageURL     string        `json:"profile_image_url"`
-	CreatedAt    time.Time     `json:"created_at"`
-	UpdatedAt    time.Time     `json:"updated_at"`
-	SocialMedias []SocialMedia `json:"social_medias,omitempty"`
-	Photos       []Photo       `json:"photos,omitempty"`
+	ID                uint          `gorm:"primaryKey" json:"id"`
+	Username          string        `json:"username"`
+	Email             string        `json:"email"`
+	Password          string        `json:"password"`
+	Age               int           `json:"age"`
+	ImageURL          string        `json:"profile_image_url"`
+	KeepPhotoMetadata bool          `json:"keep_photo_metadata"`
+	IsPrivate         bool          `json:"is_private"`
+	Role              string        `gorm:"default:'user'" json:"role"`
+	WarningCount      int           `json:"warning_count"`
+	SuspendedUntil    *time.Time    `json:"suspended_until,omitempty"`
+	Verified          bool          `json:"verified"`
+	CooldownUntil     *time.Time    `json:"cooldown_until,omitempty"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+	SocialMedias      []SocialMedia `json:"social_medias,omitempty"`
+	Photos            []Photo       `json:"photos,omitempty"`
 }
 
 type Photo struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Title     string    `json:"title"`
-	Caption   string    `json:"caption"`
-	URL       string    `json:"photo_url"`
-	UserID    uint      `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	User      User      `gorm:"foreignKey:UserID" json:"-"`
-	Comments  []Comment `json:"comments,omitempty"`
+	ID             uint                    `gorm:"primaryKey" json:"id"`
+	Title          string                  `json:"title"`
+	Caption        string                  `json:"caption"`
+	URL            string                  `json:"photo_url"`
+	StorageKey     string                  `gorm:"index" json:"storage_key,omitempty"`
+	Visibility     string                  `gorm:"default:'public'" json:"visibility"`
+	Hidden         bool                    `json:"hidden,omitempty"`
+	Status         string                  `gorm:"default:'published';index" json:"status"`
+	PublishAt      *time.Time              `json:"publish_at,omitempty"`
+	Edited         bool                    `json:"edited"`
+	EditedAt       *time.Time              `json:"edited_at,omitempty"`
+	CommentPolicy  string                  `gorm:"default:'everyone'" json:"comment_policy"`
+	PinnedComments []Comment               `gorm:"-" json:"pinned_comments,omitempty"`
+	VariantsStatus string                  `json:"variants_status,omitempty"`
+	CameraMake     string                  `json:"camera_make,omitempty"`
+	CameraModel    string                  `json:"camera_model,omitempty"`
+	TakenAt        *time.Time              `json:"taken_at,omitempty"`
+	Variants       map[string]PhotoVariant `gorm:"-" json:"variants,omitempty"`
+	BookmarkedByMe bool                    `gorm:"-" json:"bookmarked_by_me"`
+	Media          []PhotoMedia            `gorm:"-" json:"media"`
+	UserID         uint                    `json:"user_id"`
+	CreatedAt      time.Time               `json:"created_at"`
+	UpdatedAt      time.Time               `json:"updated_at"`
+	DeletedAt      *time.Time              `gorm:"index" json:"deleted_at,omitempty"`
+	User           User                    `gorm:"foreignKey:UserID" json:"-"`
+	Comments       []Comment               `json:"comments,omitempty"`
 }
 
 type Comment struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `json:"user_id"`
-	PhotoID   uint      `json:"photo_id"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	User      User      `gorm:"foreignKey:UserID" json:"-"`
-	Photo     Photo     `gorm:"foreignKey:PhotoID" json:"-"`
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	UserID       uint           `json:"user_id"`
+	PhotoID      uint           `json:"photo_id"`
+	Message      string         `json:"message"`
+	Hidden       bool           `json:"hidden,omitempty"`
+	ShadowHidden bool           `json:"-"`
+	Edited       bool           `json:"edited"`
+	EditedAt     *time.Time     `json:"edited_at,omitempty"`
+	PinnedAt     *time.Time     `json:"pinned_at,omitempty"`
+	Reactions    map[string]int `gorm:"-" json:"reactions"`
+	MyReactions  []string       `gorm:"-" json:"my_reactions"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    *time.Time     `gorm:"index" json:"deleted_at,omitempty"`
+	User         User           `gorm:"foreignKey:UserID" json:"-"`
+	Photo        Photo          `gorm:"foreignKey:PhotoID" json:"-"`
 }
@@ -0,0 +1,23 @@
+// models/photo_media.go
+package models
+
+import (
+	"time"
+)
+
+// PhotoMedia is one image of a photo post. Posts hold an ordered set of media
+// items and the first one is mirrored to Photo.URL as the cover.
+type PhotoMedia struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PhotoID    uint      `gorm:"index" json:"photo_id"`
+	Position   int       `json:"position"`
+	URL        string    `json:"url"`
+	StorageKey string    `gorm:"index" json:"storage_key,omitempty"`
+	AltText    string    `json:"alt_text"`
+	Width      int       `json:"width,omitempty"`
+	Height     int       `json:"height,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	Variants map[string]PhotoVariant `gorm:"-" json:"variants,omitempty"`
+}
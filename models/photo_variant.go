@@ -0,0 +1,21 @@
+// models/photo_variant.go
+package models
+
+import (
+	"time"
+)
+
+type PhotoVariant struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	PhotoID     uint      `json:"photo_id"`
+	SourceKey   string    `gorm:"index" json:"-"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	StorageKey  string    `gorm:"index" json:"-"`
+	URL         string    `json:"url"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Bytes       int       `json:"bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+	Photo       Photo     `gorm:"foreignKey:PhotoID" json:"-"`
+}
@@ -0,0 +1,19 @@
+// models/reaction.go
+package models
+
+import (
+	"time"
+)
+
+// ReactionEmojis is the whitelist of emoji users can react to comments with
+var ReactionEmojis = []string{"👍", "❤️", "😂", "😮", "😢", "😡", "🔥", "👏"}
+
+// CommentReaction is an emoji reaction of a user to a comment. A user can react
+// with several different emoji, each once.
+type CommentReaction struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CommentID uint      `gorm:"unique_index:idx_comment_reaction" json:"comment_id"`
+	UserID    uint      `gorm:"unique_index:idx_comment_reaction" json:"user_id"`
+	Emoji     string    `gorm:"unique_index:idx_comment_reaction" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
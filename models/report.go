@@ -0,0 +1,70 @@
+// models/report.go
+package models
+
+import (
+	"time"
+)
+
+// User roles
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+)
+
+// Report target types
+const (
+	ReportPhoto   = "photo"
+	ReportComment = "comment"
+	ReportUser    = "user"
+)
+
+// Report triage states
+const (
+	ReportOpen      = "open"
+	ReportInReview  = "in_review"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+// Moderation actions
+const (
+	ActionHide     = "hide"
+	ActionDelete   = "delete"
+	ActionWarn     = "warn"
+	ActionSuspend  = "suspend"
+	ActionTriage   = "triage"
+	ActionApprove  = "approve"
+	ActionVerify   = "verify"
+	ActionUnverify = "unverify"
+)
+
+// ReasonContentFilter is the reason of reports filed by the content filter for
+// content it held for review
+const ReasonContentFilter = "content_filter"
+
+type Report struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ReporterID  uint      `json:"reporter_id"`
+	TargetType  string    `gorm:"index:idx_report_target" json:"target_type"`
+	TargetID    uint      `gorm:"index:idx_report_target" json:"target_id"`
+	Reason      string    `json:"reason"`
+	Details     string    `gorm:"type:text" json:"details"`
+	Status      string    `gorm:"index" json:"status"`
+	ModeratorID *uint     `json:"moderator_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Reporter    User      `gorm:"foreignKey:ReporterID" json:"-"`
+}
+
+// ModerationAction is the audit trail entry of a moderator decision
+type ModerationAction struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ReportID    *uint     `json:"report_id"`
+	ModeratorID uint      `json:"moderator_id"`
+	TargetType  string    `json:"target_type"`
+	TargetID    uint      `json:"target_id"`
+	Action      string    `json:"action"`
+	Note        string    `gorm:"type:text" json:"note"`
+	CreatedAt   time.Time `json:"created_at"`
+	Moderator   User      `gorm:"foreignKey:ModeratorID" json:"-"`
+}
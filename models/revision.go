@@ -0,0 +1,23 @@
+// models/revision.go
+package models
+
+import (
+	"time"
+)
+
+// PhotoRevision keeps the title and caption a photo had before an edit
+type PhotoRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PhotoID   uint      `gorm:"index" json:"photo_id"`
+	Title     string    `json:"title"`
+	Caption   string    `json:"caption"`
+	CreatedAt time.Time `json:"replaced_at"`
+}
+
+// CommentRevision keeps the message a comment had before an edit
+type CommentRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CommentID uint      `gorm:"index" json:"comment_id"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"replaced_at"`
+}
@@ -0,0 +1,31 @@
+// models/spam.go
+package models
+
+import (
+	"time"
+)
+
+// Spam actions, from the mildest to the strictest. Shadow-hidden content is only
+// visible to its author; a cooldown also stops the author from posting for a while.
+const (
+	SpamAllow      = "allow"
+	SpamShadowHide = "shadow_hide"
+	SpamCooldown   = "cooldown"
+)
+
+// SpamScore is the spam assessment of a piece of content. The total score is the sum
+// of the points given for each signal.
+type SpamScore struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"index" json:"user_id"`
+	TargetType  string    `gorm:"index:idx_spam_score_target" json:"target_type"`
+	TargetID    uint      `gorm:"index:idx_spam_score_target" json:"target_id"`
+	Score       int       `gorm:"index" json:"score"`
+	AccountAge  int       `json:"account_age"`
+	Unverified  int       `json:"unverified"`
+	Velocity    int       `json:"velocity"`
+	Duplicates  int       `json:"duplicates"`
+	LinkDensity int       `json:"link_density"`
+	Action      string    `json:"action"`
+	CreatedAt   time.Time `json:"created_at"`
+}
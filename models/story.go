@@ -0,0 +1,31 @@
+// models/story.go
+package models
+
+import (
+	"time"
+)
+
+// Story is an uploaded image shown to other users for 24 hours, then kept in
+// its owner's archive
+type Story struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Caption    string     `json:"caption"`
+	URL        string     `json:"media_url"`
+	StorageKey string     `gorm:"index" json:"storage_key,omitempty"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`
+	ArchivedAt *time.Time `gorm:"index" json:"archived_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	ViewCount  int        `gorm:"-" json:"view_count,omitempty"`
+	Seen       bool       `gorm:"-" json:"seen"`
+}
+
+// StoryView records that a user viewed a story
+type StoryView struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	StoryID   uint      `gorm:"unique_index:idx_story_view" json:"story_id"`
+	ViewerID  uint      `gorm:"unique_index:idx_story_view" json:"viewer_id"`
+	CreatedAt time.Time `json:"viewed_at"`
+	Viewer    User      `gorm:"foreignKey:ViewerID" json:"-"`
+}
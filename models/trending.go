@@ -0,0 +1,33 @@
+// models/trending.go
+package models
+
+import (
+	"time"
+)
+
+// PhotoTag links a photo to a hashtag used in its title or caption
+type PhotoTag struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	PhotoID uint   `gorm:"unique_index:idx_photo_tag" json:"photo_id"`
+	Tag     string `gorm:"unique_index:idx_photo_tag;index" json:"tag"`
+}
+
+// PhotoScore is the popularity of a public photo within a trending window,
+// recomputed periodically by the trending worker
+type PhotoScore struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Window     string    `gorm:"column:time_window;index:idx_photo_score_window" json:"window"`
+	PhotoID    uint      `json:"photo_id"`
+	UserID     uint      `json:"user_id"`
+	Likes      int       `json:"likes"`
+	Comments   int       `json:"comments"`
+	Score      float64   `gorm:"index:idx_photo_score_window" json:"score"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// TrendingTag is a hashtag ranked by the scores of the photos using it
+type TrendingTag struct {
+	Tag        string  `json:"tag"`
+	Score      float64 `json:"score"`
+	PhotoCount int     `json:"photo_count"`
+}
@@ -0,0 +1,76 @@
+// publishing/publishing.go
+package publishing
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/search"
+	"github.com/faazabilamri7/mygram/webhooks"
+)
+
+const (
+	pollInterval = 30 * time.Second
+	batchSize    = 50
+)
+
+// ErrAlreadyPublished is returned when publishing a photo that is already public
+var ErrAlreadyPublished = errors.New("Photo is already published")
+
+// StartScheduler publishes scheduled photos once their publish time has come,
+// until the process exits
+func StartScheduler() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		publishDue()
+		<-ticker.C
+	}
+}
+
+func publishDue() {
+	var photos []models.Photo
+	err := database.GetDB().Where("status = ? AND publish_at <= ?", models.PhotoScheduled, time.Now()).
+		Order("publish_at").Limit(batchSize).Find(&photos).Error
+	if err != nil {
+		log.Printf("publishing: failed to load scheduled photos: %v", err)
+		return
+	}
+
+	for _, photo := range photos {
+		if _, err := Publish(photo); err != nil && err != ErrAlreadyPublished {
+			log.Printf("publishing: failed to publish photo %d: %v", photo.ID, err)
+		}
+	}
+}
+
+// Publish makes a draft or scheduled photo public now. The photo counts as created
+// at the time it is published, and the same webhook and search updates as for a
+// newly created photo are made.
+func Publish(photo models.Photo) (models.Photo, error) {
+	now := time.Now()
+	result := database.GetDB().Model(&models.Photo{}).
+		Where("id = ? AND status IN (?)", photo.ID, []string{models.PhotoDraft, models.PhotoScheduled}).
+		UpdateColumns(map[string]interface{}{"status": models.PhotoPublished, "publish_at": nil, "created_at": now, "updated_at": now})
+	if result.Error != nil {
+		return photo, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return photo, ErrAlreadyPublished
+	}
+
+	photo.Status = models.PhotoPublished
+	photo.PublishAt = nil
+	photo.CreatedAt = now
+	photo.UpdatedAt = now
+	database.GetDB().Where("photo_id = ?", photo.ID).Order("position, id").Find(&photo.Media)
+
+	// Notify webhook subscribers and update the search index
+	webhooks.Dispatch(webhooks.PhotoCreated, photo, photo.UserID)
+	search.IndexPhoto(photo)
+
+	return photo, nil
+}
@@ -0,0 +1,151 @@
+// ratelimit/ratelimit.go
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Route groups with their own policy
+const (
+	GroupLogin  = "login"
+	GroupWrites = "writes"
+	GroupReads  = "reads"
+)
+
+// defaultPolicies apply when the RATE_LIMIT_* variable of a group is not set
+var defaultPolicies = map[string]string{
+	GroupLogin:  "10/1m",
+	GroupWrites: "60/1m",
+	GroupReads:  "300/1m",
+}
+
+// Policy is a token bucket holding Limit tokens that refills completely over Window
+type Policy struct {
+	Limit  int
+	Window time.Duration
+}
+
+// rate returns the number of tokens added per millisecond
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / float64(p.Window.Milliseconds())
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, when not allowed
+}
+
+// Store keeps token buckets. Take refills the bucket stored under key and removes
+// one token if available.
+type Store interface {
+	Take(key string, policy Policy) (Result, error)
+}
+
+// Limiter applies the policy of each route group
+type Limiter struct {
+	Store    Store
+	Policies map[string]Policy
+}
+
+var limiter *Limiter
+
+// InitLimiter configures the store selected by RATE_LIMIT_STORE ("memory" or "redis")
+// and the policies from RATE_LIMIT_LOGIN, RATE_LIMIT_WRITES and RATE_LIMIT_READS
+func InitLimiter() {
+	var store Store
+	switch strings.ToLower(os.Getenv("RATE_LIMIT_STORE")) {
+	case "", "memory":
+		store = NewMemoryStore()
+	case "redis":
+		redisStore, err := NewRedisStore(os.Getenv("RATE_LIMIT_REDIS_URL"))
+		if err != nil {
+			panic(err.Error())
+		}
+		store = redisStore
+	default:
+		panic("Unknown RATE_LIMIT_STORE " + os.Getenv("RATE_LIMIT_STORE"))
+	}
+
+	policies := map[string]Policy{}
+	for group, def := range defaultPolicies {
+		value := os.Getenv("RATE_LIMIT_" + strings.ToUpper(group))
+		if value == "" {
+			value = def
+		}
+		if value == "off" {
+			continue
+		}
+		policy, err := ParsePolicy(value)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid RATE_LIMIT_%s: %v", strings.ToUpper(group), err))
+		}
+		policies[group] = policy
+	}
+
+	limiter = &Limiter{Store: store, Policies: policies}
+}
+
+// GetLimiter returns the configured limiter, or nil if rate limiting is not initialized
+func GetLimiter() *Limiter {
+	return limiter
+}
+
+// ParsePolicy parses a policy written as "<limit>/<window>", e.g. "60/1m"
+func ParsePolicy(value string) (Policy, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return Policy{}, fmt.Errorf("expected <limit>/<window>, got %q", value)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || limit <= 0 {
+		return Policy{}, fmt.Errorf("invalid limit %q", parts[0])
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || window < time.Millisecond {
+		return Policy{}, fmt.Errorf("invalid window %q", parts[1])
+	}
+	return Policy{Limit: limit, Window: window}, nil
+}
+
+// Allow takes a token from the bucket of the key in the group. ok is false when the
+// group is not limited.
+func (l *Limiter) Allow(group, key string) (result Result, ok bool, err error) {
+	policy, ok := l.Policies[group]
+	if !ok {
+		return Result{}, false, nil
+	}
+	result, err = l.Store.Take("ratelimit:"+group+":"+key, policy)
+	return result, true, err
+}
+
+// refill returns the tokens in a bucket elapsed milliseconds after it held tokens
+func refill(policy Policy, tokens float64, elapsed int64) float64 {
+	if elapsed > 0 {
+		tokens += float64(elapsed) * policy.rate()
+	}
+	return math.Min(tokens, float64(policy.Limit))
+}
+
+// newResult describes a bucket left with tokens after a take
+func newResult(policy Policy, allowed bool, tokens float64) Result {
+	rate := policy.rate()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     policy.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration(math.Ceil((float64(policy.Limit)-tokens)/rate)) * time.Millisecond,
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration(math.Ceil((1-tokens)/rate)) * time.Millisecond
+	}
+	return result
+}
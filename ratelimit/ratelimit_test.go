@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Policy
+		wantErr bool
+	}{
+		{"60/1m", Policy{Limit: 60, Window: time.Minute}, false},
+		{" 10 / 30s ", Policy{Limit: 10, Window: 30 * time.Second}, false},
+		{"1/1ms", Policy{Limit: 1, Window: time.Millisecond}, false},
+		{"60", Policy{}, true},
+		{"0/1m", Policy{}, true},
+		{"-5/1m", Policy{}, true},
+		{"ten/1m", Policy{}, true},
+		{"10/minute", Policy{}, true},
+		{"10/1us", Policy{}, true},
+		{"10/-1m", Policy{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePolicy(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %+v, %v; want %+v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRefill(t *testing.T) {
+	policy := Policy{Limit: 10, Window: 10 * time.Second} // one token per second
+	tests := []struct {
+		tokens  float64
+		elapsed int64
+		want    float64
+	}{
+		{0, 0, 0},
+		{0, 1000, 1},
+		{2.5, 500, 3},
+		{9, 5000, 10},
+		{4, -1000, 4}, // clock going backwards adds nothing
+		{12, 0, 10},
+	}
+	for _, tt := range tests {
+		if got := refill(policy, tt.tokens, tt.elapsed); got != tt.want {
+			t.Errorf("refill(%v, %dms) = %v, want %v", tt.tokens, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	policy := Policy{Limit: 10, Window: 10 * time.Second}
+
+	allowed := newResult(policy, true, 7.5)
+	if !allowed.Allowed || allowed.Limit != 10 || allowed.Remaining != 7 || allowed.RetryAfter != 0 {
+		t.Errorf("unexpected result %+v", allowed)
+	}
+	if allowed.Reset != 2500*time.Millisecond {
+		t.Errorf("reset = %v, want 2.5s", allowed.Reset)
+	}
+
+	denied := newResult(policy, false, 0.25)
+	if denied.Allowed || denied.Remaining != 0 {
+		t.Errorf("unexpected result %+v", denied)
+	}
+	if denied.RetryAfter != 750*time.Millisecond {
+		t.Errorf("retry after = %v, want 750ms", denied.RetryAfter)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Limit: 3, Window: time.Hour}
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take("a", policy)
+		if err != nil || !result.Allowed || result.Remaining != i {
+			t.Fatalf("take %d: %+v, %v", 3-i, result, err)
+		}
+	}
+	result, _ := store.Take("a", policy)
+	if result.Allowed || result.RetryAfter <= 0 {
+		t.Errorf("expected the fourth take to be limited, got %+v", result)
+	}
+
+	// Buckets are independent per key
+	if result, _ := store.Take("b", policy); !result.Allowed {
+		t.Error("expected another key to have its own bucket")
+	}
+
+	// An empty bucket refills over time
+	store.buckets["a"].updated = time.Now().Add(-20 * time.Minute)
+	if result, _ := store.Take("a", policy); !result.Allowed {
+		t.Errorf("expected a refilled token, got %+v", result)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Limit: 1, Window: time.Minute}
+	store.Take("old", policy)
+	store.Take("recent", policy)
+
+	store.buckets["old"].updated = time.Now().Add(-2 * time.Minute)
+	store.lastSweep = time.Now().Add(-2 * sweepInterval)
+	store.Take("recent", policy)
+
+	if _, ok := store.buckets["old"]; ok {
+		t.Error("expected the full bucket to be swept")
+	}
+	if _, ok := store.buckets["recent"]; !ok {
+		t.Error("expected the recent bucket to be kept")
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	l := &Limiter{Store: NewMemoryStore(), Policies: map[string]Policy{GroupLogin: {Limit: 1, Window: time.Minute}}}
+
+	if _, ok, err := l.Allow(GroupReads, "1"); ok || err != nil {
+		t.Errorf("a group without a policy should not be limited, got ok=%v err=%v", ok, err)
+	}
+
+	first, ok, err := l.Allow(GroupLogin, "ip:1.2.3.4")
+	if !ok || err != nil || !first.Allowed {
+		t.Fatalf("first login: %+v, %v, %v", first, ok, err)
+	}
+	second, _, _ := l.Allow(GroupLogin, "ip:1.2.3.4")
+	if second.Allowed {
+		t.Error("expected the second login to be limited")
+	}
+}
@@ -0,0 +1,295 @@
+package ratelimit
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a TCP server speaking enough RESP to test the client. reply returns
+// the raw reply to a command; an empty reply closes the connection.
+type fakeRedis struct {
+	listener net.Listener
+	reply    func(args []string) string
+
+	mu       sync.Mutex
+	accepted int
+	commands [][]string
+}
+
+func newFakeRedis(t *testing.T, reply func(args []string) string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{listener: listener, reply: reply}
+	t.Cleanup(func() { listener.Close() })
+	go f.serve()
+	return f
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.accepted++
+		f.mu.Unlock()
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, args)
+		f.mu.Unlock()
+
+		reply := f.reply(args)
+		if reply == "" {
+			return
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) url(userinfo, db string) string {
+	return "redis://" + userinfo + f.listener.Addr().String() + db
+}
+
+func (f *fakeRedis) stats() (accepted int, commands [][]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.accepted, append([][]string(nil), f.commands...)
+}
+
+func newTestStore(t *testing.T, rawURL string) *RedisStore {
+	t.Helper()
+	store, err := NewRedisStore(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    interface{}
+		wantErr string
+	}{
+		{"simple string", "+OK\r\n", "OK", ""},
+		{"error", "-ERR wrong type\r\n", nil, "redis: ERR wrong type"},
+		{"integer", ":-42\r\n", int64(-42), ""},
+		{"bulk string", "$5\r\nhe\r\no\r\n", "he\r\no", ""},
+		{"empty bulk string", "$0\r\n\r\n", "", ""},
+		{"nil bulk string", "$-1\r\n", nil, ""},
+		{"array", "*2\r\n:1\r\n$3\r\n4.5\r\n", []interface{}{int64(1), "4.5"}, ""},
+		{"nested array", "*2\r\n*1\r\n+a\r\n:2\r\n", []interface{}{[]interface{}{"a"}, int64(2)}, ""},
+		{"error in array", "*2\r\n-ERR x\r\n:2\r\n", []interface{}{nil, int64(2)}, ""},
+		{"nil array", "*-1\r\n", nil, ""},
+		{"unknown type", "?what\r\n", nil, `redis: unexpected reply "?what"`},
+		{"empty line", "\r\n", nil, "redis: empty reply"},
+		{"truncated bulk string", "$10\r\nshort\r\n", nil, "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		c := &redisConn{reader: bufio.NewReader(strings.NewReader(tt.input))}
+		got, err := c.readReply()
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, %v; want %#v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestRedisStoreTake(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		if args[0] != "EVAL" {
+			return "-ERR unexpected command\r\n"
+		}
+		return "*2\r\n:1\r\n$3\r\n4.5\r\n"
+	})
+	store := newTestStore(t, server.url("", ""))
+
+	policy := Policy{Limit: 10, Window: 10 * time.Second}
+	result, err := store.Take("ratelimit:reads:user:1", policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Allowed || result.Remaining != 4 || result.Limit != 10 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	_, commands := server.stats()
+	args := commands[0]
+	if len(args) != 8 || args[1] != takeScript || args[2] != "1" || args[3] != "ratelimit:reads:user:1" {
+		t.Fatalf("unexpected EVAL arguments %q", args)
+	}
+	if args[4] != "10" || args[5] != "0.001" || args[7] != "10000" {
+		t.Errorf("unexpected policy arguments %q", args[4:])
+	}
+}
+
+func TestRedisStoreReusesConnectionAfterErrorReply(t *testing.T) {
+	var calls int
+	var mu sync.Mutex
+	server := newFakeRedis(t, func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			return "-BUSY script running\r\n"
+		}
+		return "*2\r\n:0\r\n$1\r\n0\r\n"
+	})
+	store := newTestStore(t, server.url("", ""))
+	policy := Policy{Limit: 1, Window: time.Second}
+
+	_, err := store.Take("k", policy)
+	if _, ok := err.(redisError); !ok {
+		t.Fatalf("got %v, want an error reply", err)
+	}
+	result, err := store.Take("k", policy)
+	if err != nil || result.Allowed {
+		t.Fatalf("got %+v, %v; want a limited result", result, err)
+	}
+
+	if accepted, _ := server.stats(); accepted != 1 {
+		t.Errorf("got %d connections, want the first one reused", accepted)
+	}
+}
+
+func TestRedisStoreRedialsAfterConnectionError(t *testing.T) {
+	var calls int
+	var mu sync.Mutex
+	server := newFakeRedis(t, func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			return "" // drop the connection
+		}
+		return "*2\r\n:1\r\n$1\r\n2\r\n"
+	})
+	store := newTestStore(t, server.url("", ""))
+	policy := Policy{Limit: 3, Window: time.Second}
+
+	if _, err := store.Take("k", policy); err == nil {
+		t.Fatal("expected an error when the connection drops")
+	}
+	if _, err := store.Take("k", policy); err != nil {
+		t.Fatalf("expected a new connection to work, got %v", err)
+	}
+	if accepted, _ := server.stats(); accepted != 2 {
+		t.Errorf("got %d connections, want the broken one replaced", accepted)
+	}
+}
+
+func TestRedisStoreAuthAndSelect(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		switch args[0] {
+		case "AUTH", "SELECT":
+			return "+OK\r\n"
+		}
+		return "*2\r\n:1\r\n$1\r\n0\r\n"
+	})
+	store := newTestStore(t, server.url("limiter:s3cret@", "/2"))
+
+	if _, err := store.Take("k", Policy{Limit: 1, Window: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Take("k", Policy{Limit: 1, Window: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, commands := server.stats()
+	if len(commands) != 4 {
+		t.Fatalf("got commands %q, want AUTH and SELECT once", commands)
+	}
+	if !reflect.DeepEqual(commands[0], []string{"AUTH", "limiter", "s3cret"}) {
+		t.Errorf("got %q, want AUTH with user and password", commands[0])
+	}
+	if !reflect.DeepEqual(commands[1], []string{"SELECT", "2"}) {
+		t.Errorf("got %q, want SELECT 2", commands[1])
+	}
+}
+
+func TestRedisStoreFailedAuth(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		return "-WRONGPASS invalid password\r\n"
+	})
+	store := newTestStore(t, server.url(":wrong@", ""))
+
+	_, err := store.Take("k", Policy{Limit: 1, Window: time.Second})
+	if err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatalf("got %v, want the AUTH error", err)
+	}
+	if len(store.pool) != 0 {
+		t.Error("a connection that failed to authenticate should not be pooled")
+	}
+}
+
+func TestNewRedisStore(t *testing.T) {
+	store, err := NewRedisStore("redis://cache.internal")
+	if err != nil || store.addr != "cache.internal:6379" || store.db != 0 {
+		t.Errorf("got %+v, %v; want the default port", store, err)
+	}
+
+	for _, rawURL := range []string{"", "http://cache:6379", "redis://", "redis://cache:6379/zero", "://"} {
+		if _, err := NewRedisStore(rawURL); err == nil {
+			t.Errorf("NewRedisStore(%q): expected an error", rawURL)
+		}
+	}
+}
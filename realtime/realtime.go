@@ -0,0 +1,58 @@
+// realtime/realtime.go
+package realtime
+
+import (
+	"sync"
+)
+
+// Event is pushed to the connected clients of a user
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// subscriberBuffer is how many events a slow client may fall behind before
+// further events are dropped for it
+const subscriberBuffer = 32
+
+var (
+	mu          sync.Mutex
+	subscribers = map[uint]map[chan Event]bool{}
+)
+
+// Subscribe registers a connection of the user. The returned function must be
+// called when the connection closes.
+func Subscribe(userID uint) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	mu.Lock()
+	if subscribers[userID] == nil {
+		subscribers[userID] = map[chan Event]bool{}
+	}
+	subscribers[userID][ch] = true
+	mu.Unlock()
+
+	return ch, func() {
+		mu.Lock()
+		delete(subscribers[userID], ch)
+		if len(subscribers[userID]) == 0 {
+			delete(subscribers, userID)
+		}
+		mu.Unlock()
+	}
+}
+
+// Publish delivers the event to every connection of the users. Delivery never
+// blocks; events for a connection whose buffer is full are dropped.
+func Publish(event Event, userIDs ...uint) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, userID := range userIDs {
+		for ch := range subscribers[userID] {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
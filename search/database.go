@@ -0,0 +1,118 @@
+// search/database.go
+package search
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DatabaseIndex searches the tables directly with MySQL FULLTEXT indexes.
+// The tables are the source of truth, so Index and Remove have nothing to do.
+type DatabaseIndex struct {
+	db *gorm.DB
+}
+
+// fullTextIndexes are created by EnsureFullTextIndexes
+var fullTextIndexes = map[string]string{
+	"ft_users_username":       "ALTER TABLE users ADD FULLTEXT INDEX ft_users_username (username)",
+	"ft_photos_title_caption": "ALTER TABLE photos ADD FULLTEXT INDEX ft_photos_title_caption (title, caption)",
+	"ft_comments_message":     "ALTER TABLE comments ADD FULLTEXT INDEX ft_comments_message (message)",
+}
+
+func NewDatabaseIndex(db *gorm.DB) *DatabaseIndex {
+	return &DatabaseIndex{db: db}
+}
+
+// EnsureFullTextIndexes adds the FULLTEXT indexes the database index relies on
+func EnsureFullTextIndexes(db *gorm.DB) error {
+	for name, ddl := range fullTextIndexes {
+		var count int
+		err := db.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND index_name = ?", name).
+			Row().Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := db.Exec(ddl).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *DatabaseIndex) Index(doc Document) error { return nil }
+
+func (d *DatabaseIndex) Remove(docType string, id uint) error { return nil }
+
+func (d *DatabaseIndex) Search(q Query) ([]Hit, error) {
+	terms := Tokenize(q.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	// Every term is required and matched as a prefix
+	for i, term := range terms {
+		terms[i] = "+" + term + "*"
+	}
+	against := strings.Join(terms, " ")
+
+	queries := map[string]string{
+		TypeUser:    "SELECT id, MATCH(username) AGAINST (? IN BOOLEAN MODE) AS score FROM users WHERE MATCH(username) AGAINST (? IN BOOLEAN MODE)",
+		TypePhoto:   "SELECT id, MATCH(title, caption) AGAINST (? IN BOOLEAN MODE) AS score FROM photos WHERE deleted_at IS NULL AND MATCH(title, caption) AGAINST (? IN BOOLEAN MODE)",
+		TypeComment: "SELECT id, MATCH(message) AGAINST (? IN BOOLEAN MODE) AS score FROM comments WHERE deleted_at IS NULL AND MATCH(message) AGAINST (? IN BOOLEAN MODE)",
+	}
+
+	// Fetch enough hits of every type to merge the requested page
+	window := q.Offset + q.Limit
+	var hits []Hit
+	for _, docType := range q.Types {
+		sql, ok := queries[docType]
+		if !ok {
+			continue
+		}
+		args := []interface{}{against, against}
+		if filter, ok := q.Filters[docType]; ok {
+			sql += " AND " + filter.Condition
+			args = append(args, filter.Args...)
+		}
+		rows, err := d.db.Raw(sql+" ORDER BY score DESC, id LIMIT ?", append(args, window)...).Rows()
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			hit := Hit{Type: docType}
+			if err := rows.Scan(&hit.ID, &hit.Score); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			hits = append(hits, hit)
+		}
+		rows.Close()
+	}
+
+	return pageOf(hits, q), nil
+}
+
+// pageOf ranks hits by score and cuts out the requested page
+func pageOf(hits []Hit, q Query) []Hit {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Type != hits[j].Type {
+			return hits[i].Type < hits[j].Type
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if q.Offset >= len(hits) {
+		return nil
+	}
+	hits = hits[q.Offset:]
+	if len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits
+}
@@ -0,0 +1,146 @@
+// search/memory.go
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryIndex is an embedded inverted index kept in process memory. It is rebuilt
+// from the database on startup and updated by the handlers afterwards.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[docKey]int // term -> document -> term frequency
+	docs     map[docKey][]string       // document -> its terms, for removal
+	terms    []string                  // sorted vocabulary for prefix lookups
+	dirty    bool
+}
+
+type docKey struct {
+	Type string
+	ID   uint
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		postings: make(map[string]map[docKey]int),
+		docs:     make(map[docKey][]string),
+	}
+}
+
+func (m *MemoryIndex) Index(doc Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := docKey{Type: doc.Type, ID: doc.ID}
+	m.removeLocked(key)
+
+	terms := Tokenize(doc.Text)
+	if len(terms) == 0 {
+		return nil
+	}
+	for _, term := range terms {
+		if m.postings[term] == nil {
+			m.postings[term] = make(map[docKey]int)
+			m.dirty = true
+		}
+		m.postings[term][key]++
+	}
+	m.docs[key] = terms
+	return nil
+}
+
+func (m *MemoryIndex) Remove(docType string, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeLocked(docKey{Type: docType, ID: id})
+	return nil
+}
+
+func (m *MemoryIndex) removeLocked(key docKey) {
+	for _, term := range m.docs[key] {
+		delete(m.postings[term], key)
+		if len(m.postings[term]) == 0 {
+			delete(m.postings, term)
+			m.dirty = true
+		}
+	}
+	delete(m.docs, key)
+}
+
+// Search ranks documents containing every query term, matching the terms as
+// prefixes. Exact term matches score higher than prefix matches and rare terms
+// score higher than common ones.
+func (m *MemoryIndex) Search(q Query) ([]Hit, error) {
+	queryTerms := Tokenize(q.Text)
+	if len(queryTerms) == 0 {
+		return nil, nil
+	}
+
+	m.mu.Lock()
+	if m.dirty {
+		m.terms = m.terms[:0]
+		for term := range m.postings {
+			m.terms = append(m.terms, term)
+		}
+		sort.Strings(m.terms)
+		m.dirty = false
+	}
+	m.mu.Unlock()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(q.Types))
+	for _, docType := range q.Types {
+		wanted[docType] = true
+	}
+	total := float64(len(m.docs)) + 1
+
+	var scores map[docKey]float64
+	for _, queryTerm := range queryTerms {
+		termScores := make(map[docKey]float64)
+		start := sort.SearchStrings(m.terms, queryTerm)
+		for i := start; i < len(m.terms) && strings.HasPrefix(m.terms[i], queryTerm); i++ {
+			term := m.terms[i]
+			docs := m.postings[term]
+			idf := math.Log(total / float64(len(docs)+1))
+			boost := 0.5
+			if term == queryTerm {
+				boost = 1
+			}
+			for key, tf := range docs {
+				if !wanted[key.Type] {
+					continue
+				}
+				score := boost * (1 + math.Log(float64(tf))) * (1 + idf)
+				if score > termScores[key] {
+					termScores[key] = score
+				}
+			}
+		}
+
+		// Every query term has to match
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for key := range scores {
+			if s, ok := termScores[key]; ok {
+				scores[key] += s
+			} else {
+				delete(scores, key)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for key, score := range scores {
+		// Short documents that match are more relevant than long ones
+		score /= math.Sqrt(float64(len(m.docs[key])))
+		hits = append(hits, Hit{Type: key.Type, ID: key.ID, Score: score})
+	}
+	return pageOf(hits, q), nil
+}
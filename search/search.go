@@ -0,0 +1,153 @@
+// search/search.go
+package search
+
+import (
+	"log"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+// Document types
+const (
+	TypeUser    = "user"
+	TypePhoto   = "photo"
+	TypeComment = "comment"
+)
+
+// AllTypes lists every searchable document type
+var AllTypes = []string{TypeUser, TypePhoto, TypeComment}
+
+// Document is a searchable piece of content
+type Document struct {
+	Type string
+	ID   uint
+	Text string
+}
+
+// Query is a search request; Offset and Limit page through the ranked hits
+type Query struct {
+	Text   string
+	Types  []string
+	Offset int
+	Limit  int
+
+	// Filters restricts the hits of a document type with a condition on its table.
+	// Only the database index can apply them, so callers still check the hits.
+	Filters map[string]Filter
+}
+
+// Filter is an SQL condition with its arguments
+type Filter struct {
+	Condition string
+	Args      []interface{}
+}
+
+// Hit is a ranked search result
+type Hit struct {
+	Type  string  `json:"type"`
+	ID    uint    `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// SearchIndex indexes documents and answers ranked prefix queries
+type SearchIndex interface {
+	Index(doc Document) error
+	Remove(docType string, id uint) error
+	Search(q Query) ([]Hit, error)
+}
+
+var index SearchIndex
+
+// InitSearch configures the index selected by SEARCH_BACKEND ("database" or "memory")
+func InitSearch() {
+	switch strings.ToLower(os.Getenv("SEARCH_BACKEND")) {
+	case "", "database":
+		if err := EnsureFullTextIndexes(database.GetDB()); err != nil {
+			log.Printf("search: failed to create fulltext indexes: %v", err)
+		}
+		index = NewDatabaseIndex(database.GetDB())
+	case "memory":
+		memory := NewMemoryIndex()
+		if err := Rebuild(memory); err != nil {
+			log.Printf("search: failed to build index: %v", err)
+		}
+		index = memory
+	default:
+		panic("Unknown SEARCH_BACKEND " + os.Getenv("SEARCH_BACKEND"))
+	}
+}
+
+// GetIndex returns the configured search index
+func GetIndex() SearchIndex {
+	return index
+}
+
+// Rebuild indexes every user, photo and comment stored in the database
+func Rebuild(idx SearchIndex) error {
+	var users []models.User
+	if err := database.GetDB().Select("id, username").Find(&users).Error; err != nil {
+		return err
+	}
+	for _, user := range users {
+		idx.Index(UserDocument(user))
+	}
+
+	var photos []models.Photo
+	if err := database.GetDB().Select("id, title, caption").Find(&photos).Error; err != nil {
+		return err
+	}
+	for _, photo := range photos {
+		idx.Index(PhotoDocument(photo))
+	}
+
+	// Hidden comments are indexed once a moderator approves them
+	var comments []models.Comment
+	if err := database.GetDB().Select("id, message").Where("hidden = ? AND shadow_hidden = ?", false, false).Find(&comments).Error; err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		idx.Index(CommentDocument(comment))
+	}
+
+	return nil
+}
+
+func UserDocument(user models.User) Document {
+	return Document{Type: TypeUser, ID: user.ID, Text: user.Username}
+}
+
+func PhotoDocument(photo models.Photo) Document {
+	return Document{Type: TypePhoto, ID: photo.ID, Text: photo.Title + " " + photo.Caption}
+}
+
+func CommentDocument(comment models.Comment) Document {
+	return Document{Type: TypeComment, ID: comment.ID, Text: comment.Message}
+}
+
+// IndexUser, IndexPhoto and IndexComment keep the index in sync from the handlers;
+// failures are logged since the write itself already succeeded
+func IndexUser(user models.User) { logError(index.Index(UserDocument(user))) }
+
+func IndexPhoto(photo models.Photo) { logError(index.Index(PhotoDocument(photo))) }
+
+func IndexComment(comment models.Comment) { logError(index.Index(CommentDocument(comment))) }
+
+// Remove drops a document from the index
+func Remove(docType string, id uint) { logError(index.Remove(docType, id)) }
+
+func logError(err error) {
+	if err != nil {
+		log.Printf("search: failed to update index: %v", err)
+	}
+}
+
+// Tokenize lower-cases text and splits it into words
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
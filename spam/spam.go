@@ -0,0 +1,140 @@
+// spam/spam.go
+package spam
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faazabilamri7/mygram/contentfilter"
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+const (
+	defaultHideScore       = 50
+	defaultCooldownScore   = 80
+	defaultCooldownMinutes = 15
+
+	velocityWindow    = 10 * time.Minute
+	velocityAllowance = 4
+	duplicateWindow   = 24 * time.Hour
+)
+
+// envInt returns a positive integer from the environment, or the default
+func envInt(name string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// CooldownDuration returns how long a user cannot post after a cooldown, from SPAM_COOLDOWN_MINUTES
+func CooldownDuration() time.Duration {
+	return time.Duration(envInt("SPAM_COOLDOWN_MINUTES", defaultCooldownMinutes)) * time.Minute
+}
+
+// AssessComment scores a new or edited comment of the user. The score is not saved;
+// the target of a new comment is set once the comment is stored.
+func AssessComment(user models.User, comment models.Comment) (models.SpamScore, error) {
+	score := models.SpamScore{UserID: user.ID, TargetType: models.ReportComment}
+	db := database.GetDB()
+
+	score.AccountAge = accountAgePoints(time.Since(user.CreatedAt))
+	if !user.Verified {
+		score.Unverified = 10
+	}
+
+	// Deleted comments count too, so removing them does not reset the signals. An
+	// edited comment does not count against itself.
+	var recent int
+	err := db.Unscoped().Model(&models.Comment{}).
+		Where("user_id = ? AND id <> ? AND created_at > ?", user.ID, comment.ID, time.Now().Add(-velocityWindow)).
+		Count(&recent).Error
+	if err != nil {
+		return score, err
+	}
+	score.Velocity = velocityPoints(recent)
+
+	var duplicates int
+	err = db.Unscoped().Model(&models.Comment{}).
+		Where("user_id = ? AND id <> ? AND photo_id <> ? AND message = ? AND created_at > ?",
+			user.ID, comment.ID, comment.PhotoID, comment.Message, time.Now().Add(-duplicateWindow)).
+		Count(&duplicates).Error
+	if err != nil {
+		return score, err
+	}
+	score.Duplicates = duplicatePoints(duplicates)
+
+	score.LinkDensity = linkDensityPoints(comment.Message)
+
+	score.Score = score.AccountAge + score.Unverified + score.Velocity + score.Duplicates + score.LinkDensity
+	if user.Verified {
+		// Verified accounts get the benefit of the doubt
+		score.Score -= 20
+		if score.Score < 0 {
+			score.Score = 0
+		}
+	}
+	score.Action = action(score.Score)
+
+	return score, nil
+}
+
+// action picks the action for a score from SPAM_HIDE_SCORE and SPAM_COOLDOWN_SCORE
+func action(score int) string {
+	switch {
+	case score >= envInt("SPAM_COOLDOWN_SCORE", defaultCooldownScore):
+		return models.SpamCooldown
+	case score >= envInt("SPAM_HIDE_SCORE", defaultHideScore):
+		return models.SpamShadowHide
+	}
+	return models.SpamAllow
+}
+
+// accountAgePoints scores new accounts higher
+func accountAgePoints(age time.Duration) int {
+	switch {
+	case age < time.Hour:
+		return 30
+	case age < 24*time.Hour:
+		return 20
+	case age < 7*24*time.Hour:
+		return 10
+	}
+	return 0
+}
+
+// velocityPoints scores posting more than a few comments in the velocity window
+func velocityPoints(recent int) int {
+	if recent <= velocityAllowance {
+		return 0
+	}
+	return min(10*(recent-velocityAllowance), 40)
+}
+
+// duplicatePoints scores posting the same message on other photos
+func duplicatePoints(duplicates int) int {
+	if duplicates == 0 {
+		return 0
+	}
+	return min(20+10*(duplicates-1), 40)
+}
+
+// linkDensityPoints scores messages made mostly of links
+func linkDensityPoints(message string) int {
+	links := contentfilter.CountLinks(message)
+	if links == 0 {
+		return 0
+	}
+
+	density := float64(links) / float64(len(strings.Fields(message)))
+	switch {
+	case density >= 0.5:
+		return 30
+	case density >= 0.2:
+		return 15
+	}
+	return 5
+}
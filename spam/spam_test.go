@@ -0,0 +1,104 @@
+package spam
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faazabilamri7/mygram/models"
+)
+
+func TestAccountAgePoints(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want int
+	}{
+		{time.Minute, 30},
+		{2 * time.Hour, 20},
+		{3 * 24 * time.Hour, 10},
+		{30 * 24 * time.Hour, 0},
+	}
+	for _, tt := range tests {
+		if got := accountAgePoints(tt.age); got != tt.want {
+			t.Errorf("accountAgePoints(%v) = %d, want %d", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestVelocityPoints(t *testing.T) {
+	tests := map[int]int{0: 0, velocityAllowance: 0, velocityAllowance + 1: 10, velocityAllowance + 3: 30, 100: 40}
+	for recent, want := range tests {
+		if got := velocityPoints(recent); got != want {
+			t.Errorf("velocityPoints(%d) = %d, want %d", recent, got, want)
+		}
+	}
+}
+
+func TestDuplicatePoints(t *testing.T) {
+	tests := map[int]int{0: 0, 1: 20, 2: 30, 3: 40, 10: 40}
+	for duplicates, want := range tests {
+		if got := duplicatePoints(duplicates); got != want {
+			t.Errorf("duplicatePoints(%d) = %d, want %d", duplicates, got, want)
+		}
+	}
+}
+
+func TestLinkDensityPoints(t *testing.T) {
+	tests := []struct {
+		message string
+		want    int
+	}{
+		{"nice photo", 0},
+		{"https://spam.example", 30},
+		{"go https://spam.example", 30},
+		{"check this https://spam.example", 15},
+		{"love it, the full set is at www.example.com", 5},
+		{"what a great shot, the colors are amazing, I wrote about it at https://blog.example", 5},
+	}
+	for _, tt := range tests {
+		if got := linkDensityPoints(tt.message); got != tt.want {
+			t.Errorf("linkDensityPoints(%q) = %d, want %d", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestAction(t *testing.T) {
+	t.Setenv("SPAM_HIDE_SCORE", "")
+	t.Setenv("SPAM_COOLDOWN_SCORE", "")
+	tests := map[int]string{
+		0:                         models.SpamAllow,
+		defaultHideScore - 1:      models.SpamAllow,
+		defaultHideScore:          models.SpamShadowHide,
+		defaultCooldownScore - 1:  models.SpamShadowHide,
+		defaultCooldownScore:      models.SpamCooldown,
+		defaultCooldownScore + 50: models.SpamCooldown,
+	}
+	for score, want := range tests {
+		if got := action(score); got != want {
+			t.Errorf("action(%d) = %s, want %s", score, got, want)
+		}
+	}
+
+	t.Setenv("SPAM_HIDE_SCORE", "10")
+	t.Setenv("SPAM_COOLDOWN_SCORE", "20")
+	if got := action(15); got != models.SpamShadowHide {
+		t.Errorf("with custom thresholds, action(15) = %s, want %s", got, models.SpamShadowHide)
+	}
+	if got := action(20); got != models.SpamCooldown {
+		t.Errorf("with custom thresholds, action(20) = %s, want %s", got, models.SpamCooldown)
+	}
+}
+
+func TestCooldownDuration(t *testing.T) {
+	t.Setenv("SPAM_COOLDOWN_MINUTES", "")
+	if got := CooldownDuration(); got != defaultCooldownMinutes*time.Minute {
+		t.Errorf("default cooldown = %v", got)
+	}
+	t.Setenv("SPAM_COOLDOWN_MINUTES", "5")
+	if got := CooldownDuration(); got != 5*time.Minute {
+		t.Errorf("cooldown = %v, want 5m", got)
+	}
+	t.Setenv("SPAM_COOLDOWN_MINUTES", "-3")
+	if got := CooldownDuration(); got != defaultCooldownMinutes*time.Minute {
+		t.Errorf("negative setting gave %v, want the default", got)
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewLocalStorage(filepath.Join(root, "uploads"), "/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put("photos/1/a.jpg", []byte("image"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Get("photos/1/a.jpg")
+	if err != nil || string(data) != "image" {
+		t.Fatalf("got %q, %v", data, err)
+	}
+	if got := s.URL("photos/1/a.jpg"); got != "/uploads/photos/1/a.jpg" {
+		t.Errorf("URL = %s", got)
+	}
+
+	if err := s.Delete("photos/1/a.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("photos/1/a.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v after delete, want ErrNotFound", err)
+	}
+	if err := s.Delete("photos/1/a.jpg"); err != nil {
+		t.Errorf("deleting a missing object: %v", err)
+	}
+}
+
+func TestLocalStorageRejectsEscapingKeys(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewLocalStorage(filepath.Join(root, "uploads"), "/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(root, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{"", "/", "../secret.txt", "photos/../../secret.txt", "/etc/passwd", "photos/./a.jpg", "photos//a.jpg", "photos/"}
+	for _, key := range keys {
+		if err := s.Put(key, []byte("x"), "text/plain"); !errors.Is(err, errInvalidKey) {
+			t.Errorf("Put(%q): got %v, want errInvalidKey", key, err)
+		}
+		if _, err := s.Get(key); !errors.Is(err, errInvalidKey) {
+			t.Errorf("Get(%q): got %v, want errInvalidKey", key, err)
+		}
+		if err := s.Delete(key); !errors.Is(err, errInvalidKey) {
+			t.Errorf("Delete(%q): got %v, want errInvalidKey", key, err)
+		}
+	}
+
+	if data, _ := os.ReadFile(secret); string(data) != "secret" {
+		t.Error("a file outside the root was overwritten")
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 2 {
+		t.Errorf("got %d entries in the parent directory, want only uploads and secret.txt", len(entries))
+	}
+}
@@ -0,0 +1,61 @@
+// storage/storage.go
+package storage
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when no object is stored under the key
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores uploaded objects and knows the public URL they are served from
+type Storage interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+	URL(key string) string
+}
+
+var store Storage
+
+// InitStorage configures the backend selected by STORAGE_BACKEND ("local" or "s3")
+func InitStorage() {
+	switch strings.ToLower(os.Getenv("STORAGE_BACKEND")) {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		publicURL := os.Getenv("STORAGE_PUBLIC_URL")
+		if publicURL == "" {
+			publicURL = "/uploads"
+		}
+		local, err := NewLocalStorage(dir, publicURL)
+		if err != nil {
+			panic(err.Error())
+		}
+		store = local
+	case "s3":
+		endpoint := os.Getenv("S3_ENDPOINT")
+		bucket := os.Getenv("S3_BUCKET")
+		accessKey := os.Getenv("S3_ACCESS_KEY")
+		secretKey := os.Getenv("S3_SECRET_KEY")
+		if endpoint == "" || bucket == "" || accessKey == "" || secretKey == "" {
+			panic("One or more S3 environment variables are not set")
+		}
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		store = NewS3Storage(endpoint, region, bucket, accessKey, secretKey, os.Getenv("S3_PUBLIC_URL"))
+	default:
+		panic("Unknown STORAGE_BACKEND " + os.Getenv("STORAGE_BACKEND"))
+	}
+}
+
+// GetStorage returns the configured storage backend
+func GetStorage() Storage {
+	return store
+}
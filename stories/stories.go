@@ -0,0 +1,35 @@
+// stories/stories.go
+package stories
+
+import (
+	"log"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+// Lifetime is how long a story is shown before it moves to its owner's archive
+const Lifetime = 24 * time.Hour
+
+const archiveInterval = time.Minute
+
+// StartArchiver moves expired stories to their owners' archive until the process exits
+func StartArchiver() {
+	ticker := time.NewTicker(archiveInterval)
+	defer ticker.Stop()
+	for {
+		if err := ArchiveExpired(); err != nil {
+			log.Printf("stories: failed to archive expired stories: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// ArchiveExpired marks every story past its expiry as archived
+func ArchiveExpired() error {
+	now := time.Now()
+	return database.GetDB().Model(&models.Story{}).
+		Where("archived_at IS NULL AND expires_at <= ?", now).
+		Update("archived_at", now).Error
+}
@@ -0,0 +1,217 @@
+// trash/trash.go
+package trash
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/imaging"
+	"github.com/faazabilamri7/mygram/models"
+	"github.com/faazabilamri7/mygram/search"
+	"github.com/faazabilamri7/mygram/storage"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	defaultRetentionDays = 30
+	purgeInterval        = time.Hour
+	purgeBatchSize       = 100
+)
+
+// Retention returns how long deleted photos and comments stay in the trash,
+// configured in days with TRASH_RETENTION_DAYS
+func Retention() time.Duration {
+	days := defaultRetentionDays
+	if n, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && n >= 0 {
+		days = n
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// PurgePhoto permanently removes a photo with its comments, likes, bookmarks, hashtags, album
+// memberships, media items, revisions, stored images and variants
+func PurgePhoto(photo models.Photo) error {
+	tx := database.GetDB().Begin()
+	removeFiles, err := PurgePhotoTx(tx, photo)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	removeFiles()
+	return nil
+}
+
+// PurgePhotoTx removes the rows of a photo like PurgePhoto inside a transaction. The
+// returned function removes the stored files and must only be called once the
+// transaction is committed.
+func PurgePhotoTx(tx *gorm.DB, photo models.Photo) (func(), error) {
+	var media []models.PhotoMedia
+	if err := tx.Where("photo_id = ?", photo.ID).Find(&media).Error; err != nil {
+		return nil, err
+	}
+	var commentIDs []uint
+	if err := tx.Unscoped().Model(&models.Comment{}).Where("photo_id = ?", photo.ID).Pluck("id", &commentIDs).Error; err != nil {
+		return nil, err
+	}
+
+	err := tx.Where("photo_id = ?", photo.ID).Delete(models.AlbumPhoto{}).Error
+	if err == nil {
+		err = tx.Where("photo_id = ?", photo.ID).Delete(models.PhotoMedia{}).Error
+	}
+	if err == nil {
+		err = tx.Model(&models.Album{}).Where("cover_photo_id = ?", photo.ID).Update("cover_photo_id", nil).Error
+	}
+	if err == nil {
+		err = tx.Where("comment_id IN (SELECT id FROM comments WHERE photo_id = ?)", photo.ID).Delete(models.CommentRevision{}).Error
+	}
+	if err == nil {
+		err = tx.Where("comment_id IN (SELECT id FROM comments WHERE photo_id = ?)", photo.ID).Delete(models.CommentReaction{}).Error
+	}
+	if err == nil && len(commentIDs) > 0 {
+		err = tx.Where("target_type = ? AND target_id IN (?)", models.ReportComment, commentIDs).Delete(models.SpamScore{}).Error
+	}
+	if err == nil {
+		err = tx.Unscoped().Where("photo_id = ?", photo.ID).Delete(models.Comment{}).Error
+	}
+	if err == nil {
+		err = tx.Where("photo_id = ?", photo.ID).Delete(models.PhotoRevision{}).Error
+	}
+	if err == nil {
+		err = tx.Where("photo_id = ?", photo.ID).Delete(models.Like{}).Error
+	}
+	if err == nil {
+		err = tx.Where("photo_id = ?", photo.ID).Delete(models.Bookmark{}).Error
+	}
+	if err == nil {
+		err = tx.Where("photo_id = ?", photo.ID).Delete(models.PhotoTag{}).Error
+	}
+	if err == nil {
+		err = tx.Where("photo_id = ?", photo.ID).Delete(models.PhotoScore{}).Error
+	}
+	if err == nil {
+		err = tx.Unscoped().Delete(&photo).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		for _, id := range commentIDs {
+			search.Remove(search.TypeComment, id)
+		}
+
+		// Remove the uploaded image and its variants from storage
+		if err := imaging.DeleteVariants(photo.ID); err != nil {
+			log.Printf("trash: failed to delete variants of photo %d: %v", photo.ID, err)
+		}
+		if photo.StorageKey != "" {
+			if err := storage.GetStorage().Delete(photo.StorageKey); err != nil {
+				log.Printf("trash: failed to delete stored photo %s: %v", photo.StorageKey, err)
+			}
+		}
+		for _, item := range media {
+			if item.StorageKey == "" || item.StorageKey == photo.StorageKey {
+				continue
+			}
+			if err := storage.GetStorage().Delete(item.StorageKey); err != nil {
+				log.Printf("trash: failed to delete stored media %s: %v", item.StorageKey, err)
+			}
+		}
+	}, nil
+}
+
+// PurgeComment permanently removes a comment with its revisions, reactions and spam score
+func PurgeComment(comment models.Comment) error {
+	tx := database.GetDB().Begin()
+	if err := PurgeCommentTx(tx, comment); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// PurgeCommentTx removes a comment like PurgeComment inside a transaction
+func PurgeCommentTx(tx *gorm.DB, comment models.Comment) error {
+	err := tx.Where("comment_id = ?", comment.ID).Delete(models.CommentRevision{}).Error
+	if err == nil {
+		err = tx.Where("comment_id = ?", comment.ID).Delete(models.CommentReaction{}).Error
+	}
+	if err == nil {
+		err = tx.Where("target_type = ? AND target_id = ?", models.ReportComment, comment.ID).Delete(models.SpamScore{}).Error
+	}
+	if err == nil {
+		err = tx.Unscoped().Delete(&comment).Error
+	}
+	return err
+}
+
+// StartPurger permanently removes trashed photos and comments once they are older
+// than the retention window, until the process exits
+func StartPurger() {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		purgeExpired()
+		<-ticker.C
+	}
+}
+
+func purgeExpired() {
+	cutoff := time.Now().Add(-Retention())
+	purgeExpiredPhotos(cutoff)
+	purgeExpiredComments(cutoff)
+}
+
+// purgeExpiredPhotos purges photos trashed before cutoff in batches until none are
+// left; a failure stops the run so the same batch is not retried forever
+func purgeExpiredPhotos(cutoff time.Time) {
+	for {
+		var photos []models.Photo
+		err := database.GetDB().Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Order("id").Limit(purgeBatchSize).Find(&photos).Error
+		if err != nil {
+			log.Printf("trash: failed to load expired photos: %v", err)
+			return
+		}
+		for _, photo := range photos {
+			if err := PurgePhoto(photo); err != nil {
+				log.Printf("trash: failed to purge photo %d: %v", photo.ID, err)
+				return
+			}
+			search.Remove(search.TypePhoto, photo.ID)
+		}
+		if len(photos) < purgeBatchSize {
+			return
+		}
+	}
+}
+
+// purgeExpiredComments purges comments trashed before cutoff the same way. They go
+// one by one so their revisions, reactions and spam scores go too.
+func purgeExpiredComments(cutoff time.Time) {
+	for {
+		var comments []models.Comment
+		err := database.GetDB().Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Order("id").Limit(purgeBatchSize).Find(&comments).Error
+		if err != nil {
+			log.Printf("trash: failed to load expired comments: %v", err)
+			return
+		}
+		for _, comment := range comments {
+			if err := PurgeComment(comment); err != nil {
+				log.Printf("trash: failed to purge comment %d: %v", comment.ID, err)
+				return
+			}
+			search.Remove(search.TypeComment, comment.ID)
+		}
+		if len(comments) < purgeBatchSize {
+			return
+		}
+	}
+}
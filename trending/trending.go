@@ -0,0 +1,132 @@
+// trending/trending.go
+package trending
+
+import (
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+// DefaultWindow is used when a request does not name a window
+const DefaultWindow = "24h"
+
+// Windows are the sliding time windows trending content is computed for
+var Windows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+const (
+	defaultRefreshMinutes = 10
+	maxScoredPhotos       = 1000
+	commentWeight         = 2
+	gravity               = 1.5
+)
+
+// refreshInterval returns TRENDING_REFRESH_MINUTES as a duration
+func refreshInterval() time.Duration {
+	minutes := defaultRefreshMinutes
+	if n, err := strconv.Atoi(os.Getenv("TRENDING_REFRESH_MINUTES")); err == nil && n > 0 {
+		minutes = n
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// StartWorker recomputes the trending scores periodically until the process exits
+func StartWorker() {
+	ticker := time.NewTicker(refreshInterval())
+	defer ticker.Stop()
+	for {
+		Refresh()
+		<-ticker.C
+	}
+}
+
+// Refresh recomputes the photo scores of every window
+func Refresh() {
+	for name, length := range Windows {
+		if err := refreshWindow(name, length); err != nil {
+			log.Printf("trending: failed to refresh %s window: %v", name, err)
+		}
+	}
+}
+
+// refreshWindow scores the published public photos that were posted, liked or commented on
+// within the window. Likes and comments inside the window count towards the score,
+// which then decays with the age of the photo.
+func refreshWindow(name string, length time.Duration) error {
+	now := time.Now()
+	since := now.Add(-length)
+
+	rows, err := database.GetDB().Raw(`
+		SELECT p.id, p.user_id, p.created_at,
+			(SELECT COUNT(*) FROM likes l WHERE l.photo_id = p.id AND l.created_at >= ?) AS likes,
+			(SELECT COUNT(*) FROM comments c WHERE c.photo_id = p.id AND c.created_at >= ?
+				AND c.deleted_at IS NULL AND c.hidden = ? AND c.shadow_hidden = ?) AS comments
+		FROM photos p
+		WHERE p.deleted_at IS NULL AND p.hidden = ? AND p.visibility IN (?) AND p.status IN (?)
+			AND p.user_id NOT IN (SELECT id FROM users WHERE is_private = ?)
+			AND (p.created_at >= ?
+				OR p.id IN (SELECT photo_id FROM likes WHERE created_at >= ?)
+				OR p.id IN (SELECT photo_id FROM comments WHERE created_at >= ?))`,
+		since, since, false, false,
+		false, []string{models.PhotoPublic, ""}, []string{models.PhotoPublished, ""},
+		true,
+		since, since, since,
+	).Rows()
+	if err != nil {
+		return err
+	}
+
+	var scores []models.PhotoScore
+	for rows.Next() {
+		var score models.PhotoScore
+		var createdAt time.Time
+		if err := rows.Scan(&score.PhotoID, &score.UserID, &createdAt, &score.Likes, &score.Comments); err != nil {
+			rows.Close()
+			return err
+		}
+		score.Window = name
+		score.ComputedAt = now
+		score.Score = Score(score.Likes, score.Comments, now.Sub(createdAt), length)
+		scores = append(scores, score)
+	}
+	rows.Close()
+
+	sort.Slice(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	if len(scores) > maxScoredPhotos {
+		scores = scores[:maxScoredPhotos]
+	}
+
+	// Replace the window's scores atomically
+	tx := database.GetDB().Begin()
+	err = tx.Where("time_window = ?", name).Delete(models.PhotoScore{}).Error
+	for i := 0; err == nil && i < len(scores); i++ {
+		err = tx.Create(&scores[i]).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// Score weighs engagement against the age of a photo; the age is capped at the
+// window length so older photos with fresh engagement can still trend, and a photo
+// dated in the future counts as brand new
+func Score(likes, comments int, age, window time.Duration) float64 {
+	if age < 0 {
+		age = 0
+	}
+	if age > window {
+		age = window
+	}
+	engagement := float64(1 + likes + commentWeight*comments)
+	return engagement / math.Pow(age.Hours()+2, gravity)
+}
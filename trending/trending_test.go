@@ -0,0 +1,28 @@
+package trending
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	window := 7 * 24 * time.Hour
+	fresh := Score(10, 5, 0, window)
+
+	if got := Score(10, 5, -1000*time.Hour, window); got != fresh {
+		t.Errorf("a future photo scored %v, want the score of a new one %v", got, fresh)
+	}
+	if got := Score(10, 5, -3*time.Hour, window); math.IsNaN(got) || math.IsInf(got, 0) {
+		t.Errorf("a future photo scored %v", got)
+	}
+	if Score(10, 5, 30*24*time.Hour, window) != Score(10, 5, window, window) {
+		t.Error("ages past the window should be capped")
+	}
+	if Score(10, 5, time.Hour, window) >= fresh {
+		t.Error("older photos should score lower")
+	}
+	if Score(11, 5, time.Hour, window) <= Score(10, 5, time.Hour, window) {
+		t.Error("more likes should score higher")
+	}
+}
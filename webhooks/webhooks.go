@@ -0,0 +1,146 @@
+// webhooks/webhooks.go
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+// Event names fired by the handlers
+const (
+	PhotoCreated       = "photo.created"
+	PhotoUpdated       = "photo.updated"
+	PhotoDeleted       = "photo.deleted"
+	PhotoRestored      = "photo.restored"
+	CommentCreated     = "comment.created"
+	CommentUpdated     = "comment.updated"
+	CommentDeleted     = "comment.deleted"
+	CommentRestored    = "comment.restored"
+	SocialMediaCreated = "social_media.created"
+	SocialMediaUpdated = "social_media.updated"
+	SocialMediaDeleted = "social_media.deleted"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// AllEvents lists every event a webhook can subscribe to
+var AllEvents = []string{
+	PhotoCreated, PhotoUpdated, PhotoDeleted, PhotoRestored,
+	CommentCreated, CommentUpdated, CommentDeleted, CommentRestored,
+	SocialMediaCreated, SocialMediaUpdated, SocialMediaDeleted,
+}
+
+// Envelope is the JSON body sent to subscribers
+type Envelope struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// IsValidEvent reports whether name is a known event or the "*" wildcard
+func IsValidEvent(name string) bool {
+	if name == "*" {
+		return true
+	}
+	for _, event := range AllEvents {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeEvents validates a comma separated event list and returns it trimmed
+func NormalizeEvents(events string) (string, bool) {
+	var names []string
+	for _, name := range strings.Split(events, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !IsValidEvent(name) {
+			return "", false
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	return strings.Join(names, ","), true
+}
+
+// GenerateSecret returns a random hex encoded signing secret
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Sign computes the HMAC-SHA256 signature of "timestamp.payload" with the webhook secret
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconvFormat(timestamp)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch queues an event for every active webhook of the given users that subscribes to it
+func Dispatch(event string, data interface{}, userIDs ...uint) {
+	var hooks []models.Webhook
+	err := database.GetDB().Where("user_id IN (?) AND active = ?", userIDs, true).Find(&hooks).Error
+	if err != nil {
+		log.Printf("webhooks: failed to load subscriptions for %s: %v", event, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(Envelope{Event: event, CreatedAt: time.Now(), Data: data})
+	if err != nil {
+		log.Printf("webhooks: failed to encode %s payload: %v", event, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if !subscribes(hook, event) {
+			continue
+		}
+		delivery := models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        StatusPending,
+			NextAttemptAt: time.Now(),
+		}
+		if err := database.GetDB().Create(&delivery).Error; err != nil {
+			log.Printf("webhooks: failed to queue %s for webhook %d: %v", event, hook.ID, err)
+		}
+	}
+}
+
+func subscribes(hook models.Webhook, event string) bool {
+	for _, name := range strings.Split(hook.Events, ",") {
+		if name == "*" || name == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/faazabilamri7/mygram/models"
+)
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"event":"photo.created"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte("1700000000." + string(payload)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("s3cret", 1700000000, payload); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if Sign("other", 1700000000, payload) == want {
+		t.Error("signature does not depend on the secret")
+	}
+	if Sign("s3cret", 1700000001, payload) == want {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		0:  baseBackoff,
+		1:  baseBackoff,
+		2:  2 * baseBackoff,
+		3:  4 * baseBackoff,
+		8:  128 * baseBackoff,
+		10: 512 * baseBackoff,
+		11: maxBackoff,
+		50: maxBackoff,
+	}
+	for attempts, want := range tests {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestNormalizeEvents(t *testing.T) {
+	tests := []struct {
+		events string
+		want   string
+		ok     bool
+	}{
+		{"photo.created", "photo.created", true},
+		{" photo.created , comment.deleted,", "photo.created,comment.deleted", true},
+		{"*", "*", true},
+		{"", "", false},
+		{" , ", "", false},
+		{"photo.created,photo.liked", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeEvents(tt.events)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeEvents(%q) = %q, %v; want %q, %v", tt.events, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSubscribes(t *testing.T) {
+	hook := models.Webhook{Events: "photo.created,comment.deleted"}
+	if !subscribes(hook, CommentDeleted) || subscribes(hook, PhotoDeleted) {
+		t.Error("unexpected subscription match")
+	}
+	if !subscribes(models.Webhook{Events: "*"}, SocialMediaUpdated) {
+		t.Error("wildcard should match every event")
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	allowed := []string{
+		"https://hooks.example.com/mygram",
+		"http://93.184.216.34:8080/hook",
+		"https://[2606:2800:220:1::]/hook",
+	}
+	for _, raw := range allowed {
+		if err := ValidateURL(raw); err != nil {
+			t.Errorf("ValidateURL(%q): %v", raw, err)
+		}
+	}
+
+	rejected := []string{
+		"ftp://hooks.example.com",
+		"https://",
+		"not a url",
+		"http://localhost:8080",
+		"http://api.localhost./hook",
+		"http://127.0.0.1/hook",
+		"http://10.1.2.3/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://100.64.0.1/",
+		"http://0.0.0.0/",
+		"http://[::1]/",
+		"http://[fd00::1]/",
+		"http://[64:ff9b::a00:1]/",
+	}
+	for _, raw := range rejected {
+		if err := ValidateURL(raw); err == nil {
+			t.Errorf("ValidateURL(%q): expected an error", raw)
+		}
+	}
+}
+
+func TestIsAllowedIP(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":         true,
+		"2001:4860::8888": true,
+		"127.0.0.2":       false,
+		"192.168.1.1":     false,
+		"172.16.0.1":      false,
+		"224.0.0.1":       false,
+		"198.18.0.1":      false,
+		"255.255.255.255": false,
+		"::":              false,
+		"fe80::1":         false,
+	}
+	for raw, want := range tests {
+		if got := IsAllowedIP(net.ParseIP(raw)); got != want {
+			t.Errorf("IsAllowedIP(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestSendSignsDelivery(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	// The test server listens on loopback, which the delivery client refuses
+	defaultClient := client
+	client = server.Client()
+	defer func() { client = defaultClient }()
+
+	hook := models.Webhook{URL: server.URL, Secret: "s3cret", Active: true}
+	delivery := &models.WebhookDelivery{ID: 42, Event: PhotoCreated, Payload: `{"event":"photo.created"}`}
+	status, err := send(hook, delivery)
+	if err != nil || status != http.StatusAccepted {
+		t.Fatalf("got %d, %v", status, err)
+	}
+
+	if string(body) != delivery.Payload {
+		t.Errorf("got body %q", body)
+	}
+	if received.Header.Get("X-MyGram-Event") != PhotoCreated || received.Header.Get("X-MyGram-Delivery") != "42" {
+		t.Errorf("unexpected headers %v", received.Header)
+	}
+	timestamp, err := strconv.ParseInt(received.Header.Get("X-MyGram-Timestamp"), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid timestamp header: %v", err)
+	}
+	if want := Sign(hook.Secret, timestamp, body); received.Header.Get("X-MyGram-Signature") != want {
+		t.Errorf("got signature %s, want %s", received.Header.Get("X-MyGram-Signature"), want)
+	}
+}
+
+func TestSendFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal details", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	delivery := &models.WebhookDelivery{ID: 1, Event: PhotoCreated, Payload: "{}"}
+
+	// The delivery client refuses to connect to the loopback test server
+	status, err := send(models.Webhook{URL: server.URL, Active: true}, delivery)
+	if status != 0 || !errors.Is(err, errBlockedAddress) {
+		t.Errorf("got %d, %v; want the connection refused", status, err)
+	}
+
+	defaultClient := client
+	client = server.Client()
+	defer func() { client = defaultClient }()
+
+	status, err = send(models.Webhook{URL: server.URL, Active: true}, delivery)
+	if status != http.StatusBadGateway || err == nil {
+		t.Errorf("got %d, %v; want the error status", status, err)
+	}
+
+	if _, err := send(models.Webhook{URL: server.URL, Active: false}, delivery); err == nil {
+		t.Error("expected inactive webhooks to fail")
+	}
+}
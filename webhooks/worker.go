@@ -0,0 +1,135 @@
+// webhooks/worker.go
+package webhooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faazabilamri7/mygram/database"
+	"github.com/faazabilamri7/mygram/models"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	batchSize    = 20
+	maxAttempts  = 8
+	baseBackoff  = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+	maxBodyDrain = 2048
+)
+
+var client = newClient()
+
+// StartWorker polls the delivery queue and sends due deliveries until the process exits
+func StartWorker() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		processDue()
+	}
+}
+
+func processDue() {
+	var deliveries []models.WebhookDelivery
+	err := database.GetDB().Preload("Webhook").
+		Where("status = ? AND next_attempt_at <= ?", StatusPending, time.Now()).
+		Order("next_attempt_at").Limit(batchSize).Find(&deliveries).Error
+	if err != nil {
+		log.Printf("webhooks: failed to load pending deliveries: %v", err)
+		return
+	}
+
+	for i := range deliveries {
+		deliver(&deliveries[i])
+	}
+}
+
+func deliver(delivery *models.WebhookDelivery) {
+	start := time.Now()
+	statusCode, err := send(delivery.Webhook, delivery)
+
+	attempt := models.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		StatusCode: statusCode,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	database.GetDB().Create(&attempt)
+
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = attempt.Error
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = StatusSucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= maxAttempts || !delivery.Webhook.Active:
+		delivery.Status = StatusFailed
+	default:
+		delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts))
+	}
+
+	if err := database.GetDB().Save(delivery).Error; err != nil {
+		log.Printf("webhooks: failed to update delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send posts the delivery and returns the response status. Response bodies are not
+// kept, so webhooks cannot be used to read from the services they reach.
+func send(hook models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	if !hook.Active {
+		return 0, fmt.Errorf("webhook is inactive")
+	}
+
+	payload := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MyGram-Webhooks/1.0")
+	req.Header.Set("X-MyGram-Event", delivery.Event)
+	req.Header.Set("X-MyGram-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-MyGram-Timestamp", strconvFormat(timestamp))
+	req.Header.Set("X-MyGram-Signature", Sign(hook.Secret, timestamp, payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyDrain))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff doubles the wait after every failed attempt, capped at maxBackoff
+func backoff(attempts int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return wait
+}
+
+func strconvFormat(n int64) string {
+	return strconv.FormatInt(n, 10)
+}